@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -106,6 +107,10 @@ func main() {
 	app.AddMainOption("forceprint", 'f', glib.OptionFlagNone, glib.OptionArgNone, "forces printing input if no item is selected", "")
 	app.AddMainOption("bench", 'b', glib.OptionFlagNone, glib.OptionArgNone, "prints nanoseconds for start and displaying in both service and client", "")
 	app.AddMainOption("active", 'a', glib.OptionFlagNone, glib.OptionArgString, "active item", "")
+	app.AddMainOption("root", 'r', glib.OptionFlagNone, glib.OptionArgString, "root directories for the finder, comma separated", "")
+	app.AddMainOption("dirsonly", 'o', glib.OptionFlagNone, glib.OptionArgNone, "only show directories in the finder", "")
+	app.AddMainOption("alttab", 'A', glib.OptionFlagNone, glib.OptionArgNone, "switch windows, activates the selection when the modifier is released", "")
+	app.AddMainOption("open-with", 'w', glib.OptionFlagNone, glib.OptionArgString, "pick an application to open the file or URI with", "")
 
 	app.Connect("activate", ui.Activate(state))
 
@@ -125,6 +130,8 @@ func main() {
 		themeString := options.LookupValue("theme", glib.NewVariantString("").Type())
 		placeholderString := options.LookupValue("placeholder", glib.NewVariantString("").Type())
 		initialQueryString := options.LookupValue("query", glib.NewVariantString("").Type())
+		rootString := options.LookupValue("root", glib.NewVariantString("").Type())
+		openWithString := options.LookupValue("open-with", glib.NewVariantString("").Type())
 
 		if options.Contains("dmenu") {
 			labelColumnString := options.LookupValue("labelcolumn", glib.NewVariantString("").Type())
@@ -168,6 +175,9 @@ func main() {
 			state.ExplicitModules = append(state.ExplicitModules, "dmenu")
 			state.IsDmenu = true
 
+		} else if options.Contains("alttab") {
+			state.ExplicitModules = []string{"windows"}
+			state.AltTab = true
 		} else {
 			if modulesString != nil && modulesString.String() != "" {
 				m := strings.Split(modulesString.String(), ",")
@@ -192,6 +202,32 @@ func main() {
 			state.InitialQuery = initialQueryString.String()
 		}
 
+		if rootString != nil && rootString.String() != "" {
+			state.ExplicitRoots = []string{}
+
+			// relative roots are relative to the client, not the service
+			for _, v := range strings.Split(rootString.String(), ",") {
+				if !filepath.IsAbs(v) && !strings.HasPrefix(v, "~") && cmd.Cwd() != "" {
+					v = filepath.Join(cmd.Cwd(), v)
+				}
+
+				state.ExplicitRoots = append(state.ExplicitRoots, v)
+			}
+		}
+
+		state.ExplicitDirsOnly = options.Contains("dirsonly")
+		state.Cwd = cmd.Cwd()
+
+		if openWithString != nil && openWithString.String() != "" {
+			target := openWithString.String()
+
+			if !strings.Contains(target, "://") && !filepath.IsAbs(target) && cmd.Cwd() != "" {
+				target = filepath.Join(cmd.Cwd(), target)
+			}
+
+			state.OpenWith = target
+		}
+
 		if configString != nil && configString.String() != "" {
 			state.ExplicitConfig = configString.String()
 		}
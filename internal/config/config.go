@@ -29,12 +29,15 @@ type Config struct {
 	ForceKeyboardFocus  bool           `mapstructure:"force_keyboard_focus"`
 	AsWindow            bool           `mapstructure:"as_window"`
 	IgnoreMouse         bool           `mapstructure:"ignore_mouse"`
+	Launch              Launch         `mapstructure:"launch"`
 	List                List           `mapstructure:"list"`
+	Output              Output         `mapstructure:"output"`
 	Plugins             []Plugin       `mapstructure:"plugins"`
 	Search              Search         `mapstructure:"search"`
 	Theme               string         `mapstructure:"theme"`
 	ThemeBase           []string       `mapstructure:"theme_base"`
 	Terminal            string         `mapstructure:"terminal"`
+	TerminalTitleFlag   string         `mapstructure:"terminal_title_flag"`
 
 	Available []string `mapstructure:"-"`
 	IsService bool     `mapstructure:"-"`
@@ -45,10 +48,12 @@ type Builtins struct {
 	Calc           Calc           `mapstructure:"calc"`
 	Clipboard      Clipboard      `mapstructure:"clipboard"`
 	Commands       Commands       `mapstructure:"commands"`
+	ContentSearch  ContentSearch  `mapstructure:"content_search"`
 	CustomCommands CustomCommands `mapstructure:"custom_commands"`
 	Dmenu          Dmenu          `mapstructure:"dmenu"`
 	Emojis         Emojis         `mapstructure:"emojis"`
 	Finder         Finder         `mapstructure:"finder"`
+	Recent         Recent         `mapstructure:"recent"`
 	Runner         Runner         `mapstructure:"runner"`
 	SSH            SSH            `mapstructure:"ssh"`
 	Switcher       Switcher       `mapstructure:"switcher"`
@@ -66,10 +71,12 @@ type CustomCommands struct {
 }
 
 type CustomCommand struct {
-	Cmd      string `mapstructure:"cmd"`
-	CmdAlt   string `mapstructure:"cmd_alt"`
-	Name     string `mapstructure:"name"`
-	Terminal bool   `mapstructure:"terminal"`
+	Cmd      string   `mapstructure:"cmd"`
+	CmdAlt   string   `mapstructure:"cmd_alt"`
+	Env      []string `mapstructure:"env"`
+	Name     string   `mapstructure:"name"`
+	Output   bool     `mapstructure:"output"`
+	Terminal bool     `mapstructure:"terminal"`
 }
 
 type GeneralModule struct {
@@ -89,6 +96,8 @@ type GeneralModule struct {
 	ThemeBase          []string `mapstructure:"theme_base"`
 	Typeahead          bool     `mapstructure:"typeahead"`
 	ShowSubWhenSingle  bool     `mapstructure:"show_sub_when_single"`
+	ShowInitialEntries bool     `mapstructure:"show_initial_entries"`
+	MaxInitialEntries  int      `mapstructure:"max_initial_entries"`
 	Weight             int      `mapstructure:"weight"`
 
 	// internal
@@ -98,14 +107,32 @@ type GeneralModule struct {
 
 type Finder struct {
 	GeneralModule   `mapstructure:",squash"`
-	IgnoreGitIgnore bool `mapstructure:"ignore_gitignore"`
-	Concurrency     int  `mapstructure:"concurrency"`
+	IgnoreGitIgnore bool     `mapstructure:"ignore_gitignore"`
+	Concurrency     int      `mapstructure:"concurrency"`
+	Roots           []string `mapstructure:"roots"`
+	Excludes        []string `mapstructure:"excludes"`
+	Hidden          bool     `mapstructure:"hidden"`
+	MaxDepth        int      `mapstructure:"max_depth"`
+	FollowSymlinks  bool     `mapstructure:"follow_symlinks"`
+	DirsOnly        bool     `mapstructure:"dirs_only"`
+}
+
+type ContentSearch struct {
+	GeneralModule  `mapstructure:",squash"`
+	MaxResults     int    `mapstructure:"max_results"`
+	MaxFileSize    int64  `mapstructure:"max_file_size"`
+	Editor         string `mapstructure:"editor"`
+	EditorTerminal bool   `mapstructure:"editor_terminal"`
 }
 
 type Commands struct {
 	GeneralModule `mapstructure:",squash"`
 }
 
+type Recent struct {
+	GeneralModule `mapstructure:",squash"`
+}
+
 type Switcher struct {
 	GeneralModule `mapstructure:",squash"`
 }
@@ -116,22 +143,56 @@ type Emojis struct {
 
 type SSH struct {
 	GeneralModule `mapstructure:",squash"`
-	ConfigFile    string `mapstructure:"config_file"`
-	HostFile      string `mapstructure:"host_file"`
+	ConfigFile    string      `mapstructure:"config_file"`
+	HostFile      string      `mapstructure:"host_file"`
+	Actions       []SSHAction `mapstructure:"actions"`
+	AltAction     string      `mapstructure:"alt_action"`
+}
+
+type SSHAction struct {
+	Name     string `mapstructure:"name"`
+	Label    string `mapstructure:"label"`
+	Cmd      string `mapstructure:"cmd"`
+	Terminal bool   `mapstructure:"terminal"`
 }
 
 type Websearch struct {
-	GeneralModule `mapstructure:",squash"`
-	Engines       []string `mapstructure:"engines"`
+	GeneralModule  `mapstructure:",squash"`
+	Engines        []string                   `mapstructure:"engines"`
+	Opener         string                     `mapstructure:"opener"`
+	OpenerArgs     string                     `mapstructure:"opener_args"`
+	EngineSettings map[string]WebsearchEngine `mapstructure:"engine_settings"`
+}
+
+type WebsearchEngine struct {
+	Opener     string `mapstructure:"opener"`
+	OpenerArgs string `mapstructure:"opener_args"`
 }
 
 type Applications struct {
-	GeneralModule `mapstructure:",squash"`
-	Actions       bool `mapstructure:"actions"`
-	Cache         bool `mapstructure:"cache"`
-	PrioritizeNew bool `mapstructure:"prioritize_new"`
-	ContextAware  bool `mapstructure:"context_aware"`
-	ShowGeneric   bool `mapstructure:"show_generic"`
+	GeneralModule       `mapstructure:",squash"`
+	Actions             bool     `mapstructure:"actions"`
+	Cache               bool     `mapstructure:"cache"`
+	PrioritizeNew       bool     `mapstructure:"prioritize_new"`
+	ContextAware        bool     `mapstructure:"context_aware"`
+	ShowGeneric         bool     `mapstructure:"show_generic"`
+	FocusRunning        bool     `mapstructure:"focus_running"`
+	PackagingPreference []string `mapstructure:"packaging_preference"`
+
+	// Overrides are keyed by desktop file ID. They are read separately in Get, as viper splits keys at dots.
+	Overrides map[string]ApplicationOverride `mapstructure:"-"`
+}
+
+// ApplicationOverride changes the entries of a desktop file. Empty values keep the original ones.
+type ApplicationOverride struct {
+	Label    string   `mapstructure:"label" json:"label,omitempty"`
+	Icon     string   `mapstructure:"icon" json:"icon,omitempty"`
+	Exec     string   `mapstructure:"exec" json:"exec,omitempty"`
+	Env      []string `mapstructure:"env" json:"env,omitempty"`
+	Terminal *bool    `mapstructure:"terminal" json:"terminal,omitempty"`
+	Weight   int      `mapstructure:"weight" json:"weight,omitempty"`
+	Hidden   bool     `mapstructure:"hidden" json:"hidden,omitempty"`
+	Keywords []string `mapstructure:"keywords" json:"keywords,omitempty"`
 }
 
 type Windows struct {
@@ -158,11 +219,15 @@ type Dmenu struct {
 }
 
 type Runner struct {
-	GeneralModule `mapstructure:",squash"`
-	Excludes      []string `mapstructure:"excludes"`
-	Includes      []string `mapstructure:"includes"`
-	ShellConfig   string   `mapstructure:"shell_config"`
-	GenericEntry  bool     `mapstructure:"generic_entry"`
+	GeneralModule    `mapstructure:",squash"`
+	Excludes         []string `mapstructure:"excludes"`
+	Includes         []string `mapstructure:"includes"`
+	ShellConfig      string   `mapstructure:"shell_config"`
+	ShellAliases     bool     `mapstructure:"shell_aliases"`
+	Shell            string   `mapstructure:"shell"`
+	ShellTimeout     int      `mapstructure:"shell_timeout"`
+	CompletionEngine string   `mapstructure:"completion_engine"`
+	GenericEntry     bool     `mapstructure:"generic_entry"`
 }
 
 type Plugin struct {
@@ -182,6 +247,28 @@ type Search struct {
 	Placeholder string `mapstructure:"placeholder"`
 }
 
+type LaunchStrategy string
+
+const (
+	LaunchDefault LaunchStrategy = "default"
+	LaunchSystemd LaunchStrategy = "systemd"
+	LaunchPrefix  LaunchStrategy = "prefix"
+)
+
+// Launch configures how entries are started. "systemd" runs them in a transient systemd user scope, "prefix" runs
+// them with the given prefix, f.e. "uwsm app --".
+type Launch struct {
+	Strategy LaunchStrategy `mapstructure:"strategy"`
+	Prefix   string         `mapstructure:"prefix"`
+}
+
+// Output configures running commands to show their output as entries. Commands are killed after the timeout in
+// milliseconds.
+type Output struct {
+	Timeout  int `mapstructure:"timeout"`
+	MaxLines int `mapstructure:"max_lines"`
+}
+
 type List struct {
 	Cycle              bool `mapstructure:"cycle"`
 	MaxEntries         int  `mapstructure:"max_entries"`
@@ -240,6 +327,12 @@ func Get(config string) *Config {
 		log.Panic(err)
 	}
 
+	// the raw value keeps keys containing dots, f.e. 'firefox.desktop'
+	err = viper.UnmarshalKey("builtins.applications.overrides", &cfg.Builtins.Applications.Overrides)
+	if err != nil {
+		log.Panic(err)
+	}
+
 	go setTerminal(cfg)
 
 	return cfg
@@ -1,13 +1,16 @@
 package modules
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -30,17 +33,15 @@ type Applications struct {
 	prioritizeNew  bool
 	entries        []util.Entry
 	isContextAware bool
+	focusRunning   bool
+	overrides      map[string]config.ApplicationOverride
+	packaging      []string
 	openWindows    map[string]uint
 	wmRunning      bool
 	isWatching     bool
 	showGeneric    bool
 }
 
-type Application struct {
-	Generic util.Entry   `json:"generic,omitempty"`
-	Actions []util.Entry `json:"actions,omitempty"`
-}
-
 func (a *Applications) General() *config.GeneralModule {
 	return &a.general
 }
@@ -55,26 +56,150 @@ func (a *Applications) Setup(cfg *config.Config) bool {
 	a.prioritizeNew = cfg.Builtins.Applications.PrioritizeNew
 	a.isContextAware = cfg.Builtins.Applications.ContextAware
 	a.showGeneric = cfg.Builtins.Applications.ShowGeneric
+	a.focusRunning = cfg.Builtins.Applications.FocusRunning
+	a.overrides = cfg.Builtins.Applications.Overrides
+	a.packaging = cfg.Builtins.Applications.PackagingPreference
 	a.openWindows = make(map[string]uint)
 
 	return true
 }
 
 func (a *Applications) SetupData(cfg *config.Config, ctx context.Context) {
-	a.entries = parse(a.cache, a.actions, a.prioritizeNew, a.openWindows, a.showGeneric)
+	a.load()
 
 	if cfg.IsService {
 		go a.Watch()
 	}
 
-	if !a.wmRunning && a.isContextAware {
-		go a.RunWm()
+	if !a.wmRunning && (a.isContextAware || a.focusRunning) {
+		a.RunWm()
 	}
 
 	a.general.IsSetup = true
 	a.general.HasInitialSetup = true
 }
 
+// load sets the entries from the cache, if enabled. A stale cache is still used while the entries are rebuilt in the
+// background, so newly installed applications show up without delaying the start.
+func (a *Applications) load() {
+	if !a.cache {
+		a.rebuild("")
+		return
+	}
+
+	key := a.cacheKey()
+
+	cache, ok := readApplicationsCache()
+	if !ok {
+		a.rebuild(key)
+		return
+	}
+
+	a.mu.Lock()
+	a.entries = cache.Entries
+	a.updateOpenWindows()
+	a.mu.Unlock()
+
+	if cache.Key != key {
+		go a.rebuild(key)
+	}
+}
+
+// rebuild parses the applications and, if the cache is enabled, stores them with the given cache key.
+func (a *Applications) rebuild(key string) {
+	entries := parse(a.actions, a.prioritizeNew, a.showGeneric, a.overrides, a.packaging)
+
+	if a.cache {
+		if key == "" {
+			key = a.cacheKey()
+		}
+
+		util.ToJson(&applicationsCache{
+			Version: applicationsCacheVersion,
+			Key:     key,
+			Entries: entries,
+		}, applicationsCacheFile())
+	}
+
+	a.mu.Lock()
+	a.entries = entries
+	a.updateOpenWindows()
+	a.mu.Unlock()
+}
+
+const applicationsCacheVersion = 1
+
+type applicationsCache struct {
+	Version int
+	Key     string
+	Entries []util.Entry
+}
+
+func applicationsCacheFile() string {
+	return filepath.Join(util.CacheDir(), fmt.Sprintf("%s.json", ApplicationsName))
+}
+
+// readApplicationsCache reads the cached entries. Caches in an old format are ignored.
+func readApplicationsCache() (applicationsCache, bool) {
+	cache := applicationsCache{}
+
+	b, err := os.ReadFile(applicationsCacheFile())
+	if err != nil {
+		return cache, false
+	}
+
+	if err := json.Unmarshal(b, &cache); err != nil || cache.Version != applicationsCacheVersion {
+		return cache, false
+	}
+
+	return cache, true
+}
+
+// cacheKey hashes everything the entries depend on: the paths, sizes and modification times of the application
+// dirs and desktop files, the options, overrides, packaging preference, locale and desktop. With prioritize_new, the key also changes once an
+// application isn't new anymore.
+func (a *Applications) cacheKey() string {
+	h := fnv.New64a()
+
+	overrides, _ := json.Marshal(a.overrides)
+
+	fmt.Fprintf(h, "%d %t %t %t %s %v %v %v\n", applicationsCacheVersion, a.actions, a.prioritizeNew, a.showGeneric, overrides, a.packaging, desktopLocales(), currentDesktops())
+
+	for _, d := range xdg.ApplicationDirs {
+		filepath.WalkDir(d, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			// follows links to desktop files, like parsing does
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil
+			}
+
+			fmt.Fprintf(h, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+
+			if a.prioritizeNew && !info.IsDir() && isNewApp(path) {
+				fmt.Fprintf(h, "new\n")
+			}
+
+			return nil
+		})
+	}
+
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// isNewApp reports if the desktop file was created in the last 5 minutes.
+func isNewApp(file string) bool {
+	info, err := times.Stat(file)
+	if err != nil {
+		return false
+	}
+
+	return info.BirthTime().After(time.Now().Add(-time.Minute * 5))
+}
+
 func (a *Applications) Watch() {
 	a.isWatching = true
 
@@ -125,54 +250,112 @@ func (a *Applications) debounceParsing(interval time.Duration, input chan struct
 			shouldParse = true
 		case <-time.After(interval):
 			if shouldParse {
-				a.entries = parse(a.cache, a.actions, a.prioritizeNew, a.openWindows, a.showGeneric)
+				a.rebuild("")
 				shouldParse = false
 			}
 		}
 	}
 }
 
+// RunWm tracks the open windows, it's only started once. Context awareness and focusing running applications are
+// disabled if the compositor doesn't list its windows, other errors are retried in the background.
 func (a *Applications) RunWm() {
+	a.wmRunning = true
+
 	addChan := make(chan string)
 	deleteChan := make(chan string)
+	done := make(chan struct{})
 
-	a.wmRunning = true
-
-	go wlr.StartWM(addChan, deleteChan)
+	go func() {
+		for {
+			select {
+			case appId := <-addChan:
+				a.mu.Lock()
+				a.openWindows[strings.ToLower(appId)]++
+				a.updateOpenWindows()
+				a.mu.Unlock()
+			case appId := <-deleteChan:
+				a.mu.Lock()
+				appId = strings.ToLower(appId)
+
+				if val, ok := a.openWindows[appId]; ok {
+					if val == 1 {
+						delete(a.openWindows, appId)
+					} else {
+						a.openWindows[appId] = val - 1
+					}
+				}
 
-	for {
-		select {
-		case appId := <-addChan:
-			a.mu.Lock()
-			val, ok := a.openWindows[appId]
-
-			if ok {
-				a.openWindows[appId] = val + 1
-			} else {
-				a.openWindows[appId] = 1
+				a.updateOpenWindows()
+				a.mu.Unlock()
+			case <-done:
+				return
 			}
+		}
+	}()
 
-			for k := range a.entries {
-				if _, ok := a.openWindows[a.entries[k].InitialClass]; ok {
-					a.entries[k].OpenWindows = a.openWindows[a.entries[k].InitialClass]
-				}
-			}
+	if err := wlr.StartWM(addChan, deleteChan); errors.Is(err, wlr.ErrUnsupported) {
+		log.Printf("applications: %s, disabling context awareness", err)
 
-			a.mu.Unlock()
-		case appId := <-deleteChan:
-			a.mu.Lock()
+		close(done)
 
-			if val, ok := a.openWindows[appId]; ok {
-				if val == 1 {
-					delete(a.openWindows, appId)
-				} else {
-					a.openWindows[appId] = val - 1
-				}
-			}
+		a.isContextAware = false
+		a.focusRunning = false
+	}
+}
 
-			a.mu.Unlock()
+func (a *Applications) updateOpenWindows() {
+	for k := range a.entries {
+		a.entries[k].OpenWindows = countOpenWindows(a.openWindows, a.entries[k])
+	}
+}
+
+// windowIds returns the app_ids windows of the application may have: the StartupWMClass and the desktop file ID,
+// which Wayland clients are supposed to use.
+func windowIds(entry util.Entry) []string {
+	res := []string{}
+
+	for _, v := range []string{entry.InitialClass, strings.ToLower(strings.TrimSuffix(entry.DesktopID, ".desktop"))} {
+		if v != "" && !slices.Contains(res, v) {
+			res = append(res, v)
 		}
 	}
+
+	return res
+}
+
+func countOpenWindows(openWindows map[string]uint, entry util.Entry) uint {
+	res := uint(0)
+
+	for _, v := range windowIds(entry) {
+		res += openWindows[v]
+	}
+
+	return res
+}
+
+// FocusRunning activates an open window of the application, if there is one. Desktop actions, the entries preferred
+// over their application, always run, as they are meant to do something else, f.e. open a private window.
+func FocusRunning(entry util.Entry) bool {
+	if entry.Prefer {
+		return false
+	}
+
+	if !wlr.IsRunning() {
+		return false
+	}
+
+	id, ok := wlr.FindByAppId(windowIds(entry)...)
+	if !ok {
+		return false
+	}
+
+	if err := wlr.Activate(id); err != nil {
+		log.Printf("applications: %s", err)
+		return false
+	}
+
+	return true
 }
 
 func (a *Applications) Refresh() {
@@ -181,217 +364,176 @@ func (a *Applications) Refresh() {
 	}
 }
 
+// Entries returns a copy of the entries, as they are replaced by rebuilds and updated with the open windows in the
+// background.
 func (a *Applications) Entries(ctx context.Context, term string) []util.Entry {
-	return a.entries
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return slices.Clone(a.entries)
+}
+
+var packagingBadges = map[string]string{
+	packagingFlatpak: "Flatpak",
+	packagingSnap:    "Snap",
 }
 
-func parse(cache, actions, prioritizeNew bool, openWindows map[string]uint, showGeneric bool) []util.Entry {
-	apps := []Application{}
+// overrideKey normalizes desktop file IDs, so overrides work with or without the '.desktop' suffix.
+func overrideKey(id string) string {
+	return strings.ToLower(strings.TrimSuffix(id, ".desktop"))
+}
+
+func parse(actions, prioritizeNew bool, showGeneric bool, overrides map[string]config.ApplicationOverride, packaging []string) []util.Entry {
 	entries := []util.Entry{}
 
-	if cache {
-		ok := readCache(ApplicationsName, &entries)
-		if ok {
-			return entries
-		}
+	desktops := currentDesktops()
+
+	byID := make(map[string]config.ApplicationOverride, len(overrides))
+
+	for k, v := range overrides {
+		byID[overrideKey(k)] = v
 	}
 
-	dirs := xdg.ApplicationDirs
+	apps := []desktopApp{}
 
-	flags := []string{"%f", "%F", "%u", "%U", "%d", "%D", "%n", "%N", "%i", "%c", "%k", "%v", "%m"}
+	for _, app := range desktopApps() {
+		if app.shouldShow(desktops) {
+			apps = append(apps, app)
+		}
+	}
 
-	done := make(map[string]struct{})
+	for _, app := range dedupePackaged(apps, packaging) {
+		override := byID[overrideKey(app.ID)]
 
-	for _, d := range dirs {
-		if _, err := os.Stat(d); err != nil {
+		if override.Hidden {
 			continue
 		}
 
-		filepath.WalkDir(d, func(path string, info fs.DirEntry, err error) error {
-			if _, ok := done[info.Name()]; ok {
-				return nil
-			}
+		if override.Label != "" {
+			app.Name = override.Label
+		}
 
-			if !info.IsDir() && filepath.Ext(path) == ".desktop" {
-				file, err := os.Open(path)
-				if err != nil {
-					return err
-				}
+		if override.Icon != "" {
+			app.Icon = override.Icon
+		}
 
-				matching := util.Fuzzy
+		if override.Terminal != nil {
+			app.Terminal = *override.Terminal
+		}
 
-				if prioritizeNew {
-					if info, err := times.Stat(path); err == nil {
-						target := time.Now().Add(-time.Minute * 5)
+		app.Categories = append(slices.Clone(app.Categories), override.Keywords...)
 
-						mod := info.BirthTime()
-						if mod.After(target) {
-							matching = util.AlwaysTopOnEmptySearch
-						}
-					}
-				}
+		matching := util.Fuzzy
 
-				defer file.Close()
-				scanner := bufio.NewScanner(file)
-
-				app := Application{
-					Generic: util.Entry{
-						Class:            ApplicationsName,
-						History:          true,
-						Matching:         matching,
-						RecalculateScore: true,
-					},
-					Actions: []util.Entry{},
-				}
+		if prioritizeNew && isNewApp(app.File) {
+			matching = util.AlwaysTopOnEmptySearch
+		}
 
-				isAction := false
-				skip := false
+		categories := append(slices.Clone(app.Categories), app.Keywords...)
 
-				for scanner.Scan() {
-					line := scanner.Text()
+		// localized apps can be found by their untranslated name and keywords as well
+		searchable := ""
 
-					if strings.HasPrefix(line, "[Desktop Entry") {
-						isAction = false
-						skip = false
-						continue
-					}
+		if app.Untranslated.Name != app.Name {
+			searchable = app.Untranslated.Name
+		}
 
-					if skip {
-						continue
-					}
+		if app.Untranslated.GenericName != app.GenericName && app.Untranslated.GenericName != "" {
+			categories = append(categories, app.Untranslated.GenericName)
+		}
 
-					if strings.HasPrefix(line, "[Desktop Action") {
-						if !actions {
-							skip = true
-						}
+		for _, v := range app.Untranslated.Keywords {
+			if !slices.Contains(categories, v) {
+				categories = append(categories, v)
+			}
+		}
 
-						app.Actions = append(app.Actions, util.Entry{})
+		exec := app.command()
 
-						isAction = true
-					}
+		if override.Exec != "" {
+			exec = override.Exec
+		}
 
-					if strings.HasPrefix(line, "NoDisplay=") {
-						nodisplay := strings.TrimPrefix(line, "NoDisplay=") == "true"
+		sub := app.GenericName
+		classes := []string{}
 
-						if nodisplay {
-							done[info.Name()] = struct{}{}
-							return nil
-						}
+		// sandboxed applications get a badge
+		if badge := packagingBadges[app.Packaging]; badge != "" {
+			sub = strings.TrimSpace(fmt.Sprintf("%s (%s)", app.GenericName, badge))
 
-						continue
-					}
+			if app.GenericName == "" {
+				sub = badge
+			}
 
-					if !isAction {
-						if strings.HasPrefix(line, "Name=") {
-							app.Generic.Label = strings.TrimSpace(strings.TrimPrefix(line, "Name="))
-							continue
-						}
-
-						if strings.HasPrefix(line, "Path=") {
-							app.Generic.Path = strings.TrimSpace(strings.TrimPrefix(line, "Path="))
-							continue
-						}
-
-						if strings.HasPrefix(line, "Categories=") {
-							cats := strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "Categories=")), ";")
-							app.Generic.Categories = append(app.Generic.Categories, cats...)
-							continue
-						}
-
-						if strings.HasPrefix(line, "Keywords=") {
-							cats := strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "Keywords=")), ";")
-							app.Generic.Categories = append(app.Generic.Categories, cats...)
-							continue
-						}
-
-						if strings.HasPrefix(line, "GenericName=") {
-							app.Generic.Sub = strings.TrimSpace(strings.TrimPrefix(line, "GenericName="))
-							continue
-						}
-
-						if strings.HasPrefix(line, "Terminal=") {
-							app.Generic.Terminal = strings.TrimSpace(strings.TrimPrefix(line, "Terminal=")) == "true"
-							continue
-						}
-
-						if strings.HasPrefix(line, "StartupWMClass=") {
-							app.Generic.InitialClass = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "StartupWMClass=")))
-
-							if val, ok := openWindows[app.Generic.InitialClass]; ok {
-								app.Generic.OpenWindows = val
-							}
-
-							continue
-						}
-
-						if strings.HasPrefix(line, "Icon=") {
-							app.Generic.Icon = strings.TrimSpace(strings.TrimPrefix(line, "Icon="))
-							continue
-						}
-
-						if strings.HasPrefix(line, "Exec=") {
-							app.Generic.Exec = strings.TrimSpace(strings.TrimPrefix(line, "Exec="))
-
-							for _, v := range flags {
-								app.Generic.Exec = strings.ReplaceAll(app.Generic.Exec, v, "")
-							}
-
-							continue
-						}
-					} else {
-						if strings.HasPrefix(line, "Exec=") {
-							app.Actions[len(app.Actions)-1].Exec = strings.TrimSpace(strings.TrimPrefix(line, "Exec="))
-
-							for _, v := range flags {
-								app.Actions[len(app.Actions)-1].Exec = strings.ReplaceAll(app.Actions[len(app.Actions)-1].Exec, v, "")
-							}
-							continue
-						}
-
-						if strings.HasPrefix(line, "Name=") {
-							app.Actions[len(app.Actions)-1].Label = strings.TrimSpace(strings.TrimPrefix(line, "Name="))
-							continue
-						}
-					}
-				}
+			classes = append(classes, app.Packaging)
+		}
 
-				for k := range app.Actions {
-					sub := app.Generic.Label
+		generic := util.Entry{
+			Label:            app.Name,
+			Sub:              sub,
+			Classes:          classes,
+			Searchable:       searchable,
+			Exec:             exec,
+			Env:              override.Env,
+			Weight:           override.Weight,
+			Path:             app.Path,
+			Icon:             app.Icon,
+			Terminal:         app.Terminal,
+			DesktopID:        app.ID,
+			Categories:       categories,
+			InitialClass:     strings.ToLower(app.StartupWMClass),
+			Class:            ApplicationsName,
+			History:          true,
+			Matching:         matching,
+			RecalculateScore: true,
+		}
 
-					if showGeneric && app.Generic.Sub != "" {
-						sub = fmt.Sprintf("%s (%s)", app.Generic.Label, app.Generic.Sub)
-					}
+		entries = append(entries, generic)
 
-					app.Actions[k].Sub = sub
-					app.Actions[k].Path = app.Generic.Path
-					app.Actions[k].Icon = app.Generic.Icon
-					app.Actions[k].Terminal = app.Generic.Terminal
-					app.Actions[k].Class = ApplicationsName
-					app.Actions[k].Matching = app.Generic.Matching
-					app.Actions[k].Categories = app.Generic.Categories
-					app.Actions[k].History = app.Generic.History
-					app.Actions[k].InitialClass = app.Generic.InitialClass
-					app.Actions[k].OpenWindows = app.Generic.OpenWindows
-					app.Actions[k].Prefer = true
-					app.Actions[k].RecalculateScore = true
-				}
+		if !actions {
+			continue
+		}
 
-				apps = append(apps, app)
+		sub = app.Name
 
-				done[info.Name()] = struct{}{}
+		if showGeneric && app.GenericName != "" {
+			sub = fmt.Sprintf("%s (%s)", app.Name, app.GenericName)
+		}
+
+		for _, v := range app.Actions {
+			icon := v.Icon
+
+			if icon == "" {
+				icon = app.Icon
 			}
 
-			return nil
-		})
-	}
+			searchable := ""
 
-	for _, v := range apps {
-		entries = append(entries, v.Generic)
-		entries = append(entries, v.Actions...)
-	}
+			if v.UntranslatedName != v.Name {
+				searchable = v.UntranslatedName
+			}
 
-	if cache {
-		util.ToJson(&entries, filepath.Join(util.CacheDir(), fmt.Sprintf("%s.json", ApplicationsName)))
+			entries = append(entries, util.Entry{
+				Label:            v.Name,
+				Sub:              sub,
+				Searchable:       searchable,
+				Exec:             app.actionCommand(v),
+				Env:              override.Env,
+				Weight:           override.Weight,
+				Path:             app.Path,
+				Icon:             icon,
+				Terminal:         app.Terminal,
+				DesktopID:        app.ID,
+				Class:            ApplicationsName,
+				Matching:         matching,
+				Categories:       generic.Categories,
+				History:          true,
+				InitialClass:     generic.InitialClass,
+				Classes:          classes,
+				Prefer:           true,
+				RecalculateScore: true,
+			})
+		}
 	}
 
 	return entries
@@ -0,0 +1,338 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/abenz1267/walker/internal/config"
+	"github.com/abenz1267/walker/internal/util"
+)
+
+const (
+	contentSnippetLength = 200
+	contentBatchInterval = 100 * time.Millisecond
+)
+
+// ContentSearch searches the contents of the files below the finder roots. It uses the same walking options as the
+// finder, so ignore files, excludes and hidden files are handled the same way.
+type ContentSearch struct {
+	mu            sync.Mutex
+	general       config.GeneralModule
+	config        config.ContentSearch
+	finder        config.Finder
+	explicitRoots []string
+}
+
+type contentMatch struct {
+	path    string
+	root    string
+	line    int
+	snippet string
+}
+
+func (c *ContentSearch) General() *config.GeneralModule {
+	return &c.general
+}
+
+func (c *ContentSearch) Cleanup() {
+	dropTemporaryFileIndexes()
+}
+
+func (c *ContentSearch) Refresh() {
+	c.general.IsSetup = !c.general.Refresh
+}
+
+// SetExplicit sets the roots for the current invocation. Dirs-only mode doesn't apply to content search.
+func (c *ContentSearch) SetExplicit(roots []string, dirsOnly bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.explicitRoots = roots
+}
+
+func (c *ContentSearch) Setup(cfg *config.Config) bool {
+	c.general = cfg.Builtins.ContentSearch.GeneralModule
+	c.config = cfg.Builtins.ContentSearch
+	c.finder = cfg.Builtins.Finder
+
+	return true
+}
+
+// SetupData updates the file index in the background, unless it's kept current by the finder watching it.
+func (c *ContentSearch) SetupData(cfg *config.Config, ctx context.Context) {
+	index, _ := c.index()
+
+	if !index.isWatched() {
+		go index.walk(context.Background())
+	}
+
+	c.general.IsSetup = true
+	c.general.HasInitialSetup = true
+}
+
+// index returns the file index shared with the finder. Dirs-only mode doesn't apply to content search.
+func (c *ContentSearch) index() (*fileIndex, bool) {
+	c.mu.Lock()
+	roots, options := finderOptions(c.finder, c.explicitRoots, false)
+	explicit := len(c.explicitRoots) > 0
+	c.mu.Unlock()
+
+	options.DirsOnly = false
+
+	return sharedFileIndex(roots, options, explicit)
+}
+
+// Entries collects all streamed matches.
+func (c *ContentSearch) Entries(ctx context.Context, term string) []util.Entry {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	batches := make(chan []util.Entry)
+
+	go func() {
+		c.Stream(ctx, term, batches)
+		close(batches)
+	}()
+
+	entries := []util.Entry{}
+
+	for v := range batches {
+		entries = append(entries, v...)
+	}
+
+	return entries
+}
+
+// Stream searches the indexed files concurrently and sends the matches in batches until all files are searched,
+// the result limit is reached or the context is cancelled. Without an index yet, the roots are walked directly while
+// the index is built in the background.
+func (c *ContentSearch) Stream(ctx context.Context, term string, batches chan<- []util.Entry) {
+	term = strings.TrimSpace(term)
+
+	if term == "" {
+		return
+	}
+
+	// batches are sent until the caller cancels, the own context only stops searching once the limit is reached
+	parent := ctx
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	index, isNew := c.index()
+
+	if isNew {
+		go index.walk(context.Background())
+	}
+
+	paths, pathRoots, complete := index.paths()
+
+	type job struct {
+		path string
+		root string
+	}
+
+	jobs := make(chan job)
+	matches := make(chan contentMatch)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < max(index.options.Concurrency, 1); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				c.searchFile(ctx, j.path, j.root, term, matches)
+			}
+		}()
+	}
+
+	send := func(j job) {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		if complete {
+			for _, v := range paths {
+				send(job{path: v, root: pathRoots[v]})
+			}
+		} else {
+			for _, root := range index.roots {
+				walkFiles(ctx, root, index.options, func(path string) {
+					send(job{path: path, root: root})
+				})
+			}
+		}
+
+		close(jobs)
+		wg.Wait()
+		close(matches)
+	}()
+
+	showRoot := len(index.roots) > 1
+	count := 0
+	batch := []util.Entry{}
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		select {
+		case batches <- batch:
+		case <-parent.Done():
+		}
+
+		batch = []util.Entry{}
+	}
+
+	ticker := time.NewTicker(contentBatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m, ok := <-matches:
+			if !ok {
+				flush()
+				return
+			}
+
+			if c.config.MaxResults > 0 && count >= c.config.MaxResults {
+				cancel()
+				continue
+			}
+
+			count++
+			batch = append(batch, c.entry(m, showRoot))
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// searchFile reports every line of path containing term. The search is case-insensitive, unless term contains
+// upper case letters. Large and binary files are skipped.
+func (c *ContentSearch) searchFile(ctx context.Context, path, root, term string, matches chan<- contentMatch) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	if c.config.MaxFileSize > 0 && info.Size() > c.config.MaxFileSize {
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	head, _ := reader.Peek(512)
+	if bytes.IndexByte(head, 0) != -1 {
+		return
+	}
+
+	ignoreCase := !strings.ContainsFunc(term, unicode.IsUpper)
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		if line%1000 == 0 && ctx.Err() != nil {
+			return
+		}
+
+		text := scanner.Text()
+		toMatch := text
+
+		if ignoreCase {
+			toMatch = strings.ToLower(text)
+		}
+
+		if !strings.Contains(toMatch, term) {
+			continue
+		}
+
+		select {
+		case matches <- contentMatch{path: path, root: root, line: line, snippet: snippet(text)}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func snippet(line string) string {
+	line = strings.TrimSpace(line)
+
+	runes := []rune(line)
+
+	if len(runes) > contentSnippetLength {
+		return fmt.Sprintf("%s…", string(runes[:contentSnippetLength]))
+	}
+
+	return line
+}
+
+func (c *ContentSearch) entry(m contentMatch, showRoot bool) util.Entry {
+	rel := strings.TrimPrefix(strings.TrimPrefix(m.path, m.root), string(filepath.Separator))
+
+	sub := ""
+
+	if showRoot {
+		sub = tildePath(m.root)
+	}
+
+	path := m.path
+
+	return util.Entry{
+		Label:        fmt.Sprintf("%s:%d: %s", rel, m.line, m.snippet),
+		Sub:          sub,
+		Exec:         c.editorCmd(m.path, m.line),
+		Terminal:     c.config.EditorTerminal,
+		Path:         filepath.Dir(m.path),
+		DragDrop:     true,
+		DragDropData: m.path,
+		Class:        "content_search",
+		Matching:     util.AlwaysTop,
+		ActionsFunc: func() []util.Entry {
+			return fileActions(path)
+		},
+	}
+}
+
+// editorCmd opens path at line. The default uses $EDITOR with the '+line' argument most terminal editors support.
+func (c *ContentSearch) editorCmd(path string, line int) string {
+	tmpl := c.config.Editor
+
+	if tmpl == "" {
+		tmpl = `"${EDITOR:-vi}" +%LINE% %FILE%`
+	}
+
+	return strings.NewReplacer("%FILE%", util.ShellQuote(path), "%LINE%", strconv.Itoa(line)).Replace(tmpl)
+}
@@ -32,15 +32,23 @@ func (c *CustomCommands) SetupData(cfg *config.Config, ctx context.Context) {
 	c.entries = []util.Entry{}
 
 	for _, v := range cfg.Builtins.CustomCommands.Commands {
-		c.entries = append(c.entries, util.Entry{
+		e := util.Entry{
 			Label:            v.Name,
 			Sub:              "Commands",
 			Exec:             v.Cmd,
 			ExecAlt:          v.CmdAlt,
+			Env:              v.Env,
+			Output:           v.Output,
 			Terminal:         v.Terminal,
 			Matching:         util.Fuzzy,
 			RecalculateScore: true,
-		})
+		}
+
+		if !e.Output {
+			e.Actions = []util.Entry{outputAction(e)}
+		}
+
+		c.entries = append(c.entries, e)
 	}
 
 	c.general.IsSetup = true
@@ -0,0 +1,474 @@
+package modules
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// desktopApp is an application desktop entry.
+type desktopApp struct {
+	ID             string
+	File           string
+	Name           string
+	GenericName    string
+	Keywords       []string
+	Untranslated   desktopStrings
+	Categories     []string
+	Icon           string
+	Exec           []string
+	Path           string
+	Terminal       bool
+	StartupWMClass string
+	MimeTypes      []string
+	NoDisplay      bool
+	OnlyShowIn     []string
+	NotShowIn      []string
+	Actions        []desktopAction
+	Packaging      string
+	PackageID      string
+}
+
+const (
+	packagingNative  = "native"
+	packagingFlatpak = "flatpak"
+	packagingSnap    = "snap"
+)
+
+// desktopStrings holds the unlocalized values, so applications can be found by them as well.
+type desktopStrings struct {
+	Name        string
+	GenericName string
+	Keywords    []string
+}
+
+type desktopAction struct {
+	ID               string
+	Name             string
+	UntranslatedName string
+	Icon             string
+	Exec             []string
+}
+
+// desktopApps reads all applications from the XDG application dirs. Entries in earlier dirs shadow entries with
+// the same desktop file ID in later ones, even if they are hidden, so users can hide system entries. Entries whose
+// TryExec can't be found are skipped. Entries not meant to be displayed are kept, as they can still open files, use
+// shouldShow to filter them.
+func desktopApps() []desktopApp {
+	res := []desktopApp{}
+	done := make(map[string]struct{})
+	locales := desktopLocales()
+
+	for _, d := range xdg.ApplicationDirs {
+		if _, err := os.Stat(d); err != nil {
+			continue
+		}
+
+		filepath.WalkDir(d, func(path string, info fs.DirEntry, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".desktop" {
+				return nil
+			}
+
+			rel, err := filepath.Rel(d, path)
+			if err != nil {
+				return nil
+			}
+
+			id := strings.ReplaceAll(rel, string(filepath.Separator), "-")
+
+			if _, ok := done[id]; ok {
+				return nil
+			}
+
+			done[id] = struct{}{}
+
+			app, ok := readDesktopApp(path, locales)
+			if !ok {
+				return nil
+			}
+
+			app.ID = id
+
+			res = append(res, app)
+
+			return nil
+		})
+	}
+
+	return res
+}
+
+// readDesktopApp reads the entry, using the values for the first matching locale.
+func readDesktopApp(path string, locales []string) (desktopApp, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return desktopApp{}, false
+	}
+	defer file.Close()
+
+	entry, err := parseDesktopEntry(file)
+	if err != nil {
+		log.Printf("%s: %s", path, err)
+		return desktopApp{}, false
+	}
+
+	main := entry.main()
+
+	if main.string("Type") != "Application" || main.bool("Hidden") {
+		return desktopApp{}, false
+	}
+
+	if tryExec := main.string("TryExec"); tryExec != "" {
+		if _, err := exec.LookPath(tryExec); err != nil {
+			return desktopApp{}, false
+		}
+	}
+
+	args, err := parseExec(main.string("Exec"))
+	if err != nil {
+		return desktopApp{}, false
+	}
+
+	app := desktopApp{
+		File:        path,
+		Name:        main.localeString("Name", locales),
+		GenericName: main.localeString("GenericName", locales),
+		Keywords:    main.localeStrings("Keywords", locales),
+		Untranslated: desktopStrings{
+			Name:        main.string("Name"),
+			GenericName: main.string("GenericName"),
+			Keywords:    main.strings("Keywords"),
+		},
+		Categories:     main.strings("Categories"),
+		Icon:           main.localeString("Icon", locales),
+		Exec:           args,
+		Path:           main.string("Path"),
+		Terminal:       main.bool("Terminal"),
+		StartupWMClass: main.string("StartupWMClass"),
+		MimeTypes:      main.strings("MimeType"),
+		NoDisplay:      main.bool("NoDisplay"),
+		OnlyShowIn:     main.strings("OnlyShowIn"),
+		NotShowIn:      main.strings("NotShowIn"),
+	}
+
+	if app.Name == "" {
+		return desktopApp{}, false
+	}
+
+	app.Packaging, app.PackageID = packaging(path, main)
+	app.Exec = app.wrapExec(app.Exec)
+
+	for _, id := range main.strings("Actions") {
+		group, ok := entry.action(id)
+		if !ok {
+			continue
+		}
+
+		args, err := parseExec(group.string("Exec"))
+		if err != nil {
+			continue
+		}
+
+		action := desktopAction{
+			ID:               id,
+			Name:             group.localeString("Name", locales),
+			UntranslatedName: group.string("Name"),
+			Icon:             group.localeString("Icon", locales),
+			Exec:             app.wrapExec(args),
+		}
+
+		if action.Name == "" {
+			continue
+		}
+
+		app.Actions = append(app.Actions, action)
+	}
+
+	return app, true
+}
+
+// packaging detects applications exported by Flatpak or Snap and returns their Flatpak app ID or snap name.
+func packaging(path string, main desktopGroup) (string, string) {
+	if id := main.string("X-Flatpak"); id != "" {
+		return packagingFlatpak, id
+	}
+
+	if name := main.string("X-SnapInstanceName"); name != "" {
+		return packagingSnap, name
+	}
+
+	if strings.Contains(path, "/snapd/desktop/applications/") {
+		name, _, _ := strings.Cut(filepath.Base(path), "_")
+		return packagingSnap, name
+	}
+
+	return packagingNative, ""
+}
+
+// wrapExec makes sure commands of sandboxed applications run through their wrapper. Exported entries already do,
+// but their actions sometimes call the binary inside the sandbox directly.
+func (a desktopApp) wrapExec(args []string) []string {
+	if len(args) == 0 || a.PackageID == "" {
+		return args
+	}
+
+	switch a.Packaging {
+	case packagingFlatpak:
+		if filepath.Base(args[0]) == "flatpak" {
+			return args
+		}
+
+		return append([]string{"flatpak", "run", fmt.Sprintf("--command=%s", args[0]), a.PackageID}, args[1:]...)
+	case packagingSnap:
+		if filepath.Base(args[0]) == "snap" || slices.ContainsFunc(args, func(s string) bool { return strings.HasPrefix(s, "/snap/bin/") }) {
+			return args
+		}
+
+		app := a.PackageID
+
+		if cmd := filepath.Base(args[0]); cmd != app {
+			app = fmt.Sprintf("%s.%s", app, cmd)
+		}
+
+		return append([]string{"snap", "run", app}, args[1:]...)
+	}
+
+	return args
+}
+
+// dedupePackaged removes applications installed in several ways, f.e. natively and as Flatpak, keeping the ones with
+// the most preferred packaging. Packaging types missing in the preference come last. With an empty preference, all
+// applications are kept.
+//
+// Applications are compared by the identities in appIdentityTiers, strongest first. The first tier in which an
+// application matches one with other packaging decides, so weaker identities, and the name as the last resort, are
+// only used if the stronger ones don't tell.
+func dedupePackaged(apps []desktopApp, preference []string) []desktopApp {
+	if len(preference) == 0 {
+		return apps
+	}
+
+	rank := func(packaging string) int {
+		if i := slices.Index(preference, packaging); i != -1 {
+			return i
+		}
+
+		return len(preference)
+	}
+
+	tiers := make([]map[string][]int, len(appIdentityTiers))
+
+	for t, identities := range appIdentityTiers {
+		tiers[t] = make(map[string][]int)
+
+		for i, v := range apps {
+			for _, k := range identities(v) {
+				tiers[t][k] = append(tiers[t][k], i)
+			}
+		}
+	}
+
+	// binaries starting several applications of the same packaging, f.e. 'libreoffice', don't identify one
+	for k, indexes := range tiers[appIdentityBinary] {
+		for n, i := range indexes {
+			if slices.ContainsFunc(indexes[n+1:], func(j int) bool { return apps[j].Packaging == apps[i].Packaging }) {
+				delete(tiers[appIdentityBinary], k)
+				break
+			}
+		}
+	}
+
+	hasBetter := func(i int) bool {
+		v := apps[i]
+
+		for t, identities := range appIdentityTiers {
+			matched := false
+
+			for _, k := range identities(v) {
+				for _, j := range tiers[t][k] {
+					if apps[j].Packaging == v.Packaging {
+						continue
+					}
+
+					matched = true
+
+					if rank(apps[j].Packaging) < rank(v.Packaging) {
+						return true
+					}
+				}
+			}
+
+			if matched {
+				return false
+			}
+		}
+
+		return false
+	}
+
+	res := []desktopApp{}
+
+	for i, v := range apps {
+		if !hasBetter(i) {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+const appIdentityBinary = 2
+
+// appIdentityTiers return the lowercased keys identifying an application across packaging formats, strongest first:
+// desktop file and package IDs, StartupWMClass, the binary and, as the last resort, name and icon together, as
+// generic names like "Terminal" are shared by unrelated applications.
+var appIdentityTiers = []func(a desktopApp) []string{
+	func(a desktopApp) []string {
+		return nonEmptyLower(strings.TrimSuffix(a.ID, ".desktop"), a.PackageID)
+	},
+	func(a desktopApp) []string {
+		return nonEmptyLower(a.StartupWMClass)
+	},
+	func(a desktopApp) []string {
+		return nonEmptyLower(a.binary())
+	},
+	func(a desktopApp) []string {
+		if a.Untranslated.Name == "" || a.Icon == "" {
+			return nil
+		}
+
+		return nonEmptyLower(fmt.Sprintf("%s\x00%s", a.Untranslated.Name, a.Icon))
+	},
+}
+
+func nonEmptyLower(keys ...string) []string {
+	res := []string{}
+
+	for _, v := range keys {
+		if v != "" {
+			res = append(res, strings.ToLower(v))
+		}
+	}
+
+	return res
+}
+
+// genericBinaries run other programs, so they don't identify an application.
+var genericBinaries = []string{"env", "sh", "bash", "python", "python3", "java", "flatpak", "snap"}
+
+// binary returns the name of the program the application runs, looking through 'env' and the Flatpak and Snap
+// wrappers.
+func (a desktopApp) binary() string {
+	for _, v := range a.Exec {
+		if strings.HasPrefix(v, "--command=") && a.Packaging == packagingFlatpak {
+			return strings.TrimPrefix(v, "--command=")
+		}
+
+		if strings.HasPrefix(v, "/snap/bin/") {
+			name := filepath.Base(v)
+
+			// commands of snaps are named '<snap>.<command>'
+			if _, cmd, ok := strings.Cut(name, "."); ok {
+				return cmd
+			}
+
+			return name
+		}
+	}
+
+	for _, v := range a.Exec {
+		if strings.Contains(v, "=") || strings.HasPrefix(v, "-") {
+			continue
+		}
+
+		name := filepath.Base(v)
+
+		if !slices.Contains(genericBinaries, name) {
+			return name
+		}
+
+		// interpreters and shells run whatever follows
+		if name != "env" {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// shouldShow reports if the application should be listed in a menu on the given desktops, see
+// XDG_CURRENT_DESKTOP.
+func (a desktopApp) shouldShow(desktops []string) bool {
+	if a.NoDisplay {
+		return false
+	}
+
+	if len(a.OnlyShowIn) > 0 && !slices.ContainsFunc(a.OnlyShowIn, func(s string) bool { return slices.Contains(desktops, s) }) {
+		return false
+	}
+
+	if slices.ContainsFunc(a.NotShowIn, func(s string) bool { return slices.Contains(desktops, s) }) {
+		return false
+	}
+
+	return true
+}
+
+// currentDesktops returns the desktop names from XDG_CURRENT_DESKTOP.
+func currentDesktops() []string {
+	return strings.FieldsFunc(os.Getenv("XDG_CURRENT_DESKTOP"), func(r rune) bool { return r == ':' })
+}
+
+// handles reports if the application can open files of the given mime type, either directly, with a 'type/*'
+// wildcard or, for text files, as 'text/plain'.
+func (a desktopApp) handles(mimeType string) bool {
+	major, _, _ := strings.Cut(mimeType, "/")
+
+	for _, v := range a.MimeTypes {
+		if v == mimeType || v == major+"/*" {
+			return true
+		}
+
+		if major == "text" && v == "text/plain" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// command returns the shell command to launch the application, opening the given files or URIs.
+func (a desktopApp) command(targets ...string) string {
+	args := a.Exec
+
+	// Flatpak wraps file arguments in '@@u'/'@@' markers to forward them into the sandbox, without files they
+	// are just noise
+	if a.Packaging == packagingFlatpak && len(targets) == 0 {
+		args = slices.DeleteFunc(slices.Clone(args), func(s string) bool {
+			return s == "--file-forwarding" || s == "@@" || s == "@@u" || s == "@@f"
+		})
+	}
+
+	return shellJoin(expandExec(args, a.execContext(targets)))
+}
+
+// actionCommand returns the shell command to run the action.
+func (a desktopApp) actionCommand(action desktopAction) string {
+	return shellJoin(expandExec(action.Exec, a.execContext(nil)))
+}
+
+func (a desktopApp) execContext(targets []string) execContext {
+	return execContext{
+		targets:  targets,
+		icon:     a.Icon,
+		name:     a.Name,
+		location: a.File,
+	}
+}
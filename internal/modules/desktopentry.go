@@ -0,0 +1,396 @@
+package modules
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/abenz1267/walker/internal/util"
+)
+
+// desktopEntry is a parsed desktop entry file, see the Desktop Entry Specification. Values are stored raw, the
+// typed getters take care of unescaping.
+type desktopEntry struct {
+	groups map[string]desktopGroup
+}
+
+// desktopGroup maps keys, including their locale suffix like 'Name[de]', to raw values.
+type desktopGroup map[string]string
+
+func parseDesktopEntry(r io.Reader) (desktopEntry, error) {
+	groups, err := parseKeyFile(r)
+	if err != nil {
+		return desktopEntry{}, err
+	}
+
+	if _, ok := groups["Desktop Entry"]; !ok {
+		return desktopEntry{}, errors.New("missing [Desktop Entry] group")
+	}
+
+	return desktopEntry{groups: groups}, nil
+}
+
+// parseKeyFile parses the key file format used by desktop entries and f.e. mimeapps.list.
+func parseKeyFile(r io.Reader) (map[string]desktopGroup, error) {
+	groups := make(map[string]desktopGroup)
+
+	var group desktopGroup
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := line[1 : len(line)-1]
+
+			if _, ok := groups[name]; ok {
+				// duplicate groups are invalid, ignore the later one
+				group = nil
+				continue
+			}
+
+			group = make(desktopGroup)
+			groups[name] = group
+
+			continue
+		}
+
+		if group == nil {
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		key = strings.TrimSpace(key)
+
+		if _, ok := group[key]; ok {
+			continue
+		}
+
+		group[key] = strings.TrimSpace(val)
+	}
+
+	return groups, scanner.Err()
+}
+
+// desktopLocales returns the locale keys to look for, most specific first, following the locale matching rules of the
+// Desktop Entry Specification. The locale is taken from LC_ALL, LC_MESSAGES or LANG, in that order.
+func desktopLocales() []string {
+	locale := ""
+
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if val := os.Getenv(v); val != "" {
+			locale = val
+			break
+		}
+	}
+
+	if locale == "" || locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+		return nil
+	}
+
+	locale, modifier, hasModifier := strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	lang, country, hasCountry := strings.Cut(locale, "_")
+
+	res := []string{}
+
+	if hasCountry && hasModifier {
+		res = append(res, fmt.Sprintf("%s_%s@%s", lang, country, modifier))
+	}
+
+	if hasCountry {
+		res = append(res, fmt.Sprintf("%s_%s", lang, country))
+	}
+
+	if hasModifier {
+		res = append(res, fmt.Sprintf("%s@%s", lang, modifier))
+	}
+
+	return append(res, lang)
+}
+
+func (e desktopEntry) main() desktopGroup {
+	return e.groups["Desktop Entry"]
+}
+
+func (e desktopEntry) action(id string) (desktopGroup, bool) {
+	val, ok := e.groups[fmt.Sprintf("Desktop Action %s", id)]
+	return val, ok
+}
+
+func (g desktopGroup) string(key string) string {
+	return unescapeDesktopValue(g[key])
+}
+
+// localeString returns the value for the first matching locale key, f.e. 'Name[de_DE]', falling back to the
+// unlocalized key.
+func (g desktopGroup) localeString(key string, locales []string) string {
+	for _, v := range locales {
+		if val, ok := g[fmt.Sprintf("%s[%s]", key, v)]; ok {
+			return unescapeDesktopValue(val)
+		}
+	}
+
+	return g.string(key)
+}
+
+func (g desktopGroup) strings(key string) []string {
+	return splitDesktopList(g[key])
+}
+
+func (g desktopGroup) localeStrings(key string, locales []string) []string {
+	for _, v := range locales {
+		if val, ok := g[fmt.Sprintf("%s[%s]", key, v)]; ok {
+			return splitDesktopList(val)
+		}
+	}
+
+	return g.strings(key)
+}
+
+func (g desktopGroup) bool(key string) bool {
+	return g[key] == "true"
+}
+
+func unescapeDesktopValue(val string) string {
+	if !strings.Contains(val, `\`) {
+		return val
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		if val[i] != '\\' || i == len(val)-1 {
+			b.WriteByte(val[i])
+			continue
+		}
+
+		i++
+
+		switch val[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(val[i])
+		}
+	}
+
+	return b.String()
+}
+
+// splitDesktopList splits a list at unescaped semicolons and unescapes the values.
+func splitDesktopList(val string) []string {
+	res := []string{}
+
+	var b strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		switch {
+		case val[i] == '\\' && i < len(val)-1 && val[i+1] == ';':
+			b.WriteByte(';')
+			i++
+		case val[i] == '\\' && i < len(val)-1:
+			b.WriteByte(val[i])
+			b.WriteByte(val[i+1])
+			i++
+		case val[i] == ';':
+			if b.Len() > 0 {
+				res = append(res, unescapeDesktopValue(b.String()))
+			}
+
+			b.Reset()
+		default:
+			b.WriteByte(val[i])
+		}
+	}
+
+	if b.Len() > 0 {
+		res = append(res, unescapeDesktopValue(b.String()))
+	}
+
+	return res
+}
+
+// parseExec splits an already unescaped Exec value into its arguments. Arguments may be quoted with double quotes,
+// inside of which '"', '`', '$' and '\' are escaped with a backslash.
+func parseExec(exec string) ([]string, error) {
+	args := []string{}
+
+	var b strings.Builder
+
+	inArg := false
+	inQuotes := false
+
+	for i := 0; i < len(exec); i++ {
+		c := exec[i]
+
+		switch {
+		case inQuotes && c == '\\' && i < len(exec)-1 && strings.ContainsRune("\"`$\\", rune(exec[i+1])):
+			b.WriteByte(exec[i+1])
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case !inQuotes && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inQuotes {
+		return nil, errors.New("unterminated quote in Exec")
+	}
+
+	if inArg {
+		args = append(args, b.String())
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("empty Exec")
+	}
+
+	return args, nil
+}
+
+// execContext holds the values field codes are expanded to.
+type execContext struct {
+	targets  []string
+	icon     string
+	name     string
+	location string
+}
+
+// expandExec expands the field codes in args. Targets are passed as paths for '%f'/'%F' and as URIs for
+// '%u'/'%U'. If there is no field code for targets, they are appended.
+func expandExec(args []string, ctx execContext) []string {
+	res := []string{}
+	hasTarget := false
+
+	for _, arg := range args {
+		switch arg {
+		case "%f", "%u":
+			hasTarget = true
+
+			if len(ctx.targets) > 0 {
+				res = append(res, execTarget(ctx.targets[0], arg == "%u"))
+			}
+
+			continue
+		case "%F", "%U":
+			hasTarget = true
+
+			for _, v := range ctx.targets {
+				res = append(res, execTarget(v, arg == "%U"))
+			}
+
+			continue
+		case "%i":
+			if ctx.icon != "" {
+				res = append(res, "--icon", ctx.icon)
+			}
+
+			continue
+		}
+
+		var b strings.Builder
+
+		for i := 0; i < len(arg); i++ {
+			if arg[i] != '%' || i == len(arg)-1 {
+				b.WriteByte(arg[i])
+				continue
+			}
+
+			i++
+
+			switch arg[i] {
+			case '%':
+				b.WriteByte('%')
+			case 'f', 'u':
+				hasTarget = true
+
+				if len(ctx.targets) > 0 {
+					b.WriteString(execTarget(ctx.targets[0], arg[i] == 'u'))
+				}
+			case 'c':
+				b.WriteString(ctx.name)
+			case 'k':
+				b.WriteString(ctx.location)
+			}
+		}
+
+		if b.Len() > 0 {
+			res = append(res, b.String())
+		}
+	}
+
+	if !hasTarget {
+		res = append(res, ctx.targets...)
+	}
+
+	return res
+}
+
+// execTarget converts a path to a file URI or a file URI to a path. Other URIs are passed as is.
+func execTarget(target string, asURI bool) string {
+	if asURI {
+		if strings.HasPrefix(target, "/") {
+			return fileURI(target)
+		}
+
+		return target
+	}
+
+	if strings.HasPrefix(target, "file://") {
+		if u, err := url.Parse(target); err == nil {
+			return u.Path
+		}
+	}
+
+	return target
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin quotes the arguments where needed, so they can be run with 'sh -c'.
+func shellJoin(args []string) string {
+	res := make([]string, len(args))
+
+	for k, v := range args {
+		if shellSafe.MatchString(v) {
+			res[k] = v
+		} else {
+			res[k] = util.ShellQuote(v)
+		}
+	}
+
+	return strings.Join(res, " ")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -49,16 +50,45 @@ func (d Dmenu) Entries(ctx context.Context, term string) []util.Entry {
 			}
 		}
 
-		entries = append(entries, util.Entry{
+		e := util.Entry{
 			Label: label,
 			Sub:   "Dmenu",
 			Exec:  v,
-		})
+		}
+
+		if target, ok := openTarget(v); ok {
+			e.ActionsFunc = func() []util.Entry {
+				return OpenWithEntries(target)
+			}
+		}
+
+		entries = append(entries, e)
 	}
 
 	return entries
 }
 
+// openTarget returns the file or URI a line refers to, so it can be opened with an application. Relative paths are
+// skipped, as they are relative to the client.
+func openTarget(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, "~/") {
+		home, _ := os.UserHomeDir()
+		line = filepath.Join(home, line[2:])
+	}
+
+	if filepath.IsAbs(line) {
+		return line, util.FileExists(line)
+	}
+
+	if u, err := url.Parse(line); err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "") {
+		return line, true
+	}
+
+	return "", false
+}
+
 func (Dmenu) Reply(res string) {
 	if !util.FileExists(DmenuSocketAddrReply) {
 		return
@@ -0,0 +1,122 @@
+package modules
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/abenz1267/walker/internal/util"
+)
+
+// fileMimeType guesses the mime type of path by its extension.
+func fileMimeType(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "inode/directory"
+	}
+
+	t := mime.TypeByExtension(filepath.Ext(path))
+	if t == "" {
+		return "application/octet-stream"
+	}
+
+	t, _, err := mime.ParseMediaType(t)
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return t
+}
+
+// mimeIcon returns the generic icon for a mime type. Only names from the icon naming specification are used, so
+// every theme has them.
+func mimeIcon(mimeType string) string {
+	major, minor, _ := strings.Cut(mimeType, "/")
+
+	switch {
+	case mimeType == "inode/directory":
+		return "folder"
+	case slices.Contains([]string{"audio", "font", "image", "text", "video"}, major):
+		return fmt.Sprintf("%s-x-generic", major)
+	case strings.Contains(minor, "spreadsheet") || strings.Contains(minor, "excel"):
+		return "x-office-spreadsheet"
+	case strings.Contains(minor, "presentation") || strings.Contains(minor, "powerpoint"):
+		return "x-office-presentation"
+	case minor == "pdf" || minor == "msword" || strings.Contains(minor, "document"):
+		return "x-office-document"
+	case slices.Contains([]string{"zip", "gzip", "x-tar", "x-bzip2", "x-xz", "x-7z-compressed", "vnd.rar", "x-rar", "zstd"}, minor):
+		return "package-x-generic"
+	case minor == "x-executable" || minor == "x-sharedlib":
+		return "application-x-executable"
+	default:
+		return "text-x-generic"
+	}
+}
+
+func fileURI(path string) string {
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// fileActions returns the actions for a file or directory. They are created when requested, as looking up the
+// applications for every file up front would be too expensive.
+func fileActions(path string) []util.Entry {
+	dir := filepath.Dir(path)
+
+	res := OpenWithEntries(path)
+
+	if hasBin("dbus-send") {
+		// commas would split the array argument of dbus-send
+		uri := strings.ReplaceAll(fileURI(path), ",", "%2C")
+
+		res = append(res, util.Entry{
+			Label: "Reveal in file manager",
+			Exec: fmt.Sprintf(
+				"dbus-send --session --dest=org.freedesktop.FileManager1 --type=method_call /org/freedesktop/FileManager1 org.freedesktop.FileManager1.ShowItems array:string:%s string:'' || xdg-open %s",
+				util.ShellQuote(uri), util.ShellQuote(dir),
+			),
+		})
+	} else {
+		res = append(res, util.Entry{
+			Label: "Open containing folder",
+			Exec:  fmt.Sprintf("xdg-open %s", util.ShellQuote(dir)),
+		})
+	}
+
+	if hasBin("wl-copy") {
+		res = append(res, util.Entry{
+			Label: "Copy path",
+			Exec:  "wl-copy",
+			Piped: util.Piped{Content: path, Type: "string"},
+		}, util.Entry{
+			Label: "Copy file",
+			Exec:  "wl-copy --type text/uri-list",
+			Piped: util.Piped{Content: fmt.Sprintf("%s\r\n", fileURI(path)), Type: "string"},
+		})
+	}
+
+	res = append(res, util.Entry{
+		Label:    "Open terminal here",
+		Exec:     `"${SHELL:-sh}"`,
+		Terminal: true,
+		Path:     dir,
+	})
+
+	if hasBin("gio") {
+		res = append(res, util.Entry{
+			Label: "Move to trash",
+			Exec:  fmt.Sprintf("gio trash %s", util.ShellQuote(path)),
+		})
+	}
+
+	return res
+}
+
+func hasBin(bin string) bool {
+	path, _ := exec.LookPath(bin)
+	return path != ""
+}
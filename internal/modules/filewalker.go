@@ -0,0 +1,328 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	gitignore "github.com/boyter/gocodewalker/go-gitignore"
+)
+
+type fileWalkOptions struct {
+	IgnoreGitIgnore bool
+	Hidden          bool
+	MaxDepth        int
+	FollowSymlinks  bool
+	DirsOnly        bool
+	Excludes        []string
+	Concurrency     int
+}
+
+// key identifies the options that change the result of a walk.
+func (o fileWalkOptions) key() string {
+	return fmt.Sprintf("%t %t %d %t %t %s", o.IgnoreGitIgnore, o.Hidden, o.MaxDepth, o.FollowSymlinks, o.DirsOnly, strings.Join(o.Excludes, ":"))
+}
+
+// fileWalker walks a finder root. '.gitignore' and '.ignore' files are handled like gocodewalker does, using its
+// parser: the last matching pattern wins, '.ignore' files take precedence over '.gitignore' files and the latter are
+// only respected if 'ignore_gitignore' is disabled.
+//
+// gocodewalker itself can't do the traversal: it doesn't stop descending at a depth, only reports files, so
+// directories can't be listed, treats symlinks to directories as files and can't check a single path, which the
+// watcher needs to add created files by the same rules.
+type fileWalker struct {
+	root    string
+	options fileWalkOptions
+	found   func(path string)
+	mu      sync.Mutex
+	visited sync.Map
+	sem     chan struct{}
+}
+
+// walkFiles calls found for every file below root, or for every directory in dirs-only mode. It returns false if
+// walking was cancelled.
+func walkFiles(ctx context.Context, root string, options fileWalkOptions, found func(path string)) bool {
+	w := newFileWalker(root, options, found)
+
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		w.visited.Store(real, struct{}{})
+	}
+
+	w.walkDir(ctx, root, 0, ignoreFiles{})
+
+	return ctx.Err() == nil
+}
+
+func newFileWalker(root string, options fileWalkOptions, found func(path string)) *fileWalker {
+	w := &fileWalker{
+		root:    root,
+		options: options,
+		found:   found,
+	}
+
+	if options.Concurrency > 1 {
+		w.sem = make(chan struct{}, options.Concurrency)
+	}
+
+	return w
+}
+
+func (w *fileWalker) walkDir(ctx context.Context, dir string, depth int, ignores ignoreFiles) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	ignores = w.loadIgnores(dir, entries, ignores)
+
+	var wg sync.WaitGroup
+
+	for _, v := range entries {
+		path := filepath.Join(dir, v.Name())
+		isDir := v.IsDir()
+		isSymlink := v.Type()&fs.ModeSymlink != 0
+
+		if isSymlink {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+
+			isDir = info.IsDir()
+		}
+
+		if !isDir && !v.Type().IsRegular() && !isSymlink {
+			continue
+		}
+
+		if w.skip(path, depth+1, isDir, ignores) {
+			continue
+		}
+
+		if !w.visit(path, depth+1, isDir, isSymlink) {
+			continue
+		}
+
+		if depth == 0 && w.sem != nil {
+			wg.Add(1)
+			w.sem <- struct{}{}
+
+			go func(path string) {
+				defer func() {
+					<-w.sem
+					wg.Done()
+				}()
+
+				w.walkDir(ctx, path, depth+1, ignores)
+			}(path)
+
+			continue
+		}
+
+		w.walkDir(ctx, path, depth+1, ignores)
+	}
+
+	wg.Wait()
+}
+
+// visit emits path if it's a result and reports if it's a directory that should be walked.
+func (w *fileWalker) visit(path string, depth int, isDir, isSymlink bool) bool {
+	if !isDir || (isSymlink && !w.options.FollowSymlinks) {
+		if !w.options.DirsOnly || isDir {
+			w.emit(path)
+		}
+
+		return false
+	}
+
+	if w.options.DirsOnly {
+		w.emit(path)
+	}
+
+	if w.options.MaxDepth > 0 && depth >= w.options.MaxDepth {
+		return false
+	}
+
+	if isSymlink {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return false
+		}
+
+		if _, loaded := w.visited.LoadOrStore(real, struct{}{}); loaded {
+			return false
+		}
+	}
+
+	return true
+}
+
+// walkPath handles a single path below the root, f.e. one created after walking, as if it was reached by walking.
+func (w *fileWalker) walkPath(ctx context.Context, path string, isDir bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+
+	depth := w.depth(path)
+
+	if w.visit(path, depth, isDir, info.Mode()&fs.ModeSymlink != 0) {
+		w.walkDir(ctx, path, depth, w.ignoresFor(filepath.Dir(path)))
+	}
+}
+
+func (w *fileWalker) emit(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.found(path)
+}
+
+// skip checks hidden files, depth, excludes and ignore files. Excludes containing a '/' are matched against the
+// path relative to the root, all others against the file name.
+func (w *fileWalker) skip(path string, depth int, isDir bool, ignores ignoreFiles) bool {
+	name := filepath.Base(path)
+
+	if !w.options.Hidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+
+	if w.options.MaxDepth > 0 && depth > w.options.MaxDepth {
+		return true
+	}
+
+	if len(w.options.Excludes) > 0 {
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			rel = path
+		}
+
+		for _, v := range w.options.Excludes {
+			toMatch := name
+
+			if strings.Contains(v, "/") {
+				toMatch = rel
+			}
+
+			if ok, _ := filepath.Match(v, toMatch); ok {
+				return true
+			}
+		}
+	}
+
+	ignored := false
+
+	for _, list := range [][]gitignore.GitIgnore{ignores.git, ignores.other} {
+		for _, v := range list {
+			if m := v.Absolute(path, isDir); m != nil {
+				ignored = m.Ignore()
+			}
+		}
+	}
+
+	return ignored
+}
+
+// ignoreFiles are the parsed ignore files of a directory and its parents, '.gitignore' and '.ignore' files are kept
+// apart, as the latter take precedence.
+type ignoreFiles struct {
+	git   []gitignore.GitIgnore
+	other []gitignore.GitIgnore
+}
+
+func (w *fileWalker) loadIgnores(dir string, entries []fs.DirEntry, ignores ignoreFiles) ignoreFiles {
+	res := ignores
+
+	for _, v := range entries {
+		name := v.Name()
+
+		if v.IsDir() || (name != ".gitignore" && name != ".ignore") {
+			continue
+		}
+
+		if name == ".gitignore" && w.options.IgnoreGitIgnore {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		// copy, so sibling directories don't share the appended slices
+		if name == ".gitignore" {
+			res.git = append(slices.Clone(ignores.git), gitignore.New(file, dir, nil))
+		} else {
+			res.other = append(slices.Clone(ignores.other), gitignore.New(file, dir, nil))
+		}
+
+		file.Close()
+	}
+
+	return res
+}
+
+// ignoresFor collects the ignore files from the root down to dir, so single paths can be checked.
+func (w *fileWalker) ignoresFor(dir string) ignoreFiles {
+	dirs := []string{}
+
+	for d := dir; strings.HasPrefix(d, w.root); d = filepath.Dir(d) {
+		dirs = append([]string{d}, dirs...)
+
+		if d == w.root {
+			break
+		}
+	}
+
+	ignores := ignoreFiles{}
+
+	for _, v := range dirs {
+		entries, err := os.ReadDir(v)
+		if err != nil {
+			continue
+		}
+
+		ignores = w.loadIgnores(v, entries, ignores)
+	}
+
+	return ignores
+}
+
+// check reports if a single path, f.e. one reported by fsnotify, would have been found by walking.
+func (w *fileWalker) check(path string, isDir bool) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	elems := strings.Split(rel, string(filepath.Separator))
+
+	if !w.options.Hidden {
+		for _, v := range elems {
+			if strings.HasPrefix(v, ".") {
+				return false
+			}
+		}
+	}
+
+	return !w.skip(path, len(elems), isDir, w.ignoresFor(filepath.Dir(path)))
+}
+
+// depth returns the depth of path below the root, direct children have a depth of 1.
+func (w *fileWalker) depth(path string) int {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+
+	return len(strings.Split(rel, string(filepath.Separator)))
+}
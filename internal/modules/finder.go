@@ -5,38 +5,75 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/abenz1267/walker/internal/config"
 	"github.com/abenz1267/walker/internal/util"
-	"github.com/boyter/gocodewalker"
 )
 
 type Finder struct {
-	mutex       sync.Mutex
-	general     config.GeneralModule
-	concurrency int
-	entries     []util.Entry
+	mutex      sync.Mutex
+	general    config.GeneralModule
+	config     config.Finder
+	current    *fileIndex
+	defaultKey string
+	isWatching bool
+
+	// per invocation, set by the UI
+	explicitRoots    []string
+	explicitDirsOnly bool
 }
 
 func (f *Finder) General() *config.GeneralModule {
 	return &f.general
 }
 
-func (f *Finder) Cleanup() {}
+// Cleanup drops the indexes of per-invocation roots. They are persisted, so they are available right away when used
+// again.
+func (f *Finder) Cleanup() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	dropTemporaryFileIndexes()
+
+	f.current = nil
+}
+
+// SetExplicit sets the roots and dirs-only mode for the current invocation. Empty roots use the configured ones.
+func (f *Finder) SetExplicit(roots []string, dirsOnly bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.explicitRoots = roots
+	f.explicitDirsOnly = dirsOnly
+	f.current = nil
+}
 
 func (f *Finder) Refresh() {
 	f.general.IsSetup = !f.general.Refresh
 }
 
 func (f *Finder) Entries(ctx context.Context, term string) []util.Entry {
-	return f.entries
+	f.mutex.Lock()
+	index, isNew := f.index()
+	f.mutex.Unlock()
+
+	if isNew {
+		go index.walk(context.Background())
+	}
+
+	return index.entries()
 }
 
 func (f *Finder) Setup(cfg *config.Config) bool {
 	f.general = cfg.Builtins.Finder.GeneralModule
-	f.concurrency = cfg.Builtins.Finder.Concurrency
+	f.config = cfg.Builtins.Finder
+
+	roots, options := finderOptions(f.config, nil, false)
+	f.defaultKey = indexKey(roots, options)
 
 	if cfg.Builtins.Finder.EagerLoading {
 		go f.SetupData(cfg, context.Background())
@@ -45,64 +82,134 @@ func (f *Finder) Setup(cfg *config.Config) bool {
 	return true
 }
 
+// SetupData serves the persisted index right away and updates it in the background. In service mode the default
+// index is kept current by watching the filesystem instead.
 func (f *Finder) SetupData(cfg *config.Config, ctx context.Context) {
-	f.entries = []util.Entry{}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 
-	homedir, err := os.UserHomeDir()
-	if err != nil {
-		log.Panic(err)
+	f.general.IsSetup = true
+	f.general.HasInitialSetup = true
+
+	index, _ := f.index()
+
+	isDefault := indexKey(index.roots, index.options) == f.defaultKey
+
+	if isDefault && f.isWatching {
+		return
 	}
 
-	fileListQueue := make(chan *gocodewalker.File)
+	go index.walk(context.Background())
 
-	fileWalker := gocodewalker.NewFileWalker(homedir, fileListQueue)
-	fileWalker.IgnoreGitIgnore = cfg.Builtins.Finder.IgnoreGitIgnore
+	if isDefault && cfg.IsService {
+		f.isWatching = true
+		go index.watch()
+	}
+}
 
-	errorHandler := func(e error) bool {
-		return true
+// index returns the index for the current invocation and creates it if needed. Has to be called with the mutex held.
+func (f *Finder) index() (*fileIndex, bool) {
+	if f.current != nil {
+		return f.current, false
 	}
 
-	fileWalker.SetConcurrency(f.concurrency)
+	roots, options := finderOptions(f.config, f.explicitRoots, f.explicitDirsOnly)
+	key := indexKey(roots, options)
+
+	index, isNew := sharedFileIndex(roots, options, key != f.defaultKey)
+	f.current = index
 
-	fileWalker.SetErrorHandler(errorHandler)
+	return index, isNew
+}
+
+// finderOptions returns the roots and walk options, with explicit roots and dirs-only mode overriding the config.
+func finderOptions(cfg config.Finder, roots []string, dirsOnly bool) ([]string, fileWalkOptions) {
+	if len(roots) == 0 {
+		roots = cfg.Roots
+	}
 
-	done := make(chan bool)
+	res := []string{}
 
-	go func(isWalking chan bool) {
-		err := fileWalker.Start()
-		if err == nil {
-			isWalking <- false
+	for _, v := range roots {
+		root, err := expandRoot(v)
+		if err != nil {
+			log.Println(err)
+			continue
 		}
-	}(done)
-
-	for {
-		select {
-		case <-done:
-			fileWalker.Terminate()
-			f.general.IsSetup = true
-			f.general.HasInitialSetup = true
-			return
-		case <-ctx.Done():
-			fileWalker.Terminate()
-			f.general.IsSetup = true
-			f.general.HasInitialSetup = true
-			return
-		case file := <-fileListQueue:
-			if file == nil {
-				continue
-			}
-
-			f.entries = append(f.entries, util.Entry{
-				Label:            strings.TrimPrefix(strings.TrimPrefix(file.Location, homedir), "/"),
-				Sub:              "finder",
-				Exec:             fmt.Sprintf("xdg-open %s", file.Location),
-				RecalculateScore: true,
-				DragDrop:         true,
-				DragDropData:     file.Location,
-				Categories:       []string{"finder", "fzf"},
-				Class:            "finder",
-				Matching:         util.Fuzzy,
-			})
+
+		if !slices.Contains(res, root) {
+			res = append(res, root)
+		}
+	}
+
+	if len(res) == 0 {
+		homedir, err := os.UserHomeDir()
+		if err != nil {
+			log.Panic(err)
+		}
+
+		res = append(res, homedir)
+	}
+
+	options := fileWalkOptions{
+		IgnoreGitIgnore: cfg.IgnoreGitIgnore,
+		Hidden:          cfg.Hidden,
+		MaxDepth:        cfg.MaxDepth,
+		FollowSymlinks:  cfg.FollowSymlinks,
+		DirsOnly:        cfg.DirsOnly || dirsOnly,
+		Excludes:        cfg.Excludes,
+		Concurrency:     cfg.Concurrency,
+	}
+
+	return res, options
+}
+
+func indexKey(roots []string, options fileWalkOptions) string {
+	return fmt.Sprintf("%s %s", strings.Join(roots, ":"), options.key())
+}
+
+// expandRoot expands a leading '~' and makes the root absolute.
+func expandRoot(root string) (string, error) {
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
 		}
+
+		root = filepath.Join(home, strings.TrimPrefix(root, "~"))
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return "", fmt.Errorf("finder: %s is not a directory", root)
 	}
+
+	return root, nil
+}
+
+// tildePath shortens paths in the home directory for display.
+func tildePath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+
+	if path == home {
+		return "~"
+	}
+
+	if strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+
+	return path
 }
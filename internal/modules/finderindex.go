@@ -0,0 +1,486 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abenz1267/walker/internal/util"
+	"github.com/fsnotify/fsnotify"
+)
+
+const finderIndexVersion = 2
+
+// fileIndex is the persistent list of files below the finder roots. It's stored in the cache dir, so it's available
+// instantly, and kept current by re-walking or, in service mode, by watching the indexed directories.
+type fileIndex struct {
+	mu          sync.Mutex
+	temporary   bool
+	complete    bool
+	roots       []string
+	options     fileWalkOptions
+	cacheFile   string
+	files       map[string]string
+	snapshot    []util.Entry
+	dirty       bool
+	isWalking   bool
+	rescanning  bool
+	changes     []fileChange
+	watcher     *fsnotify.Watcher
+	watchMu     sync.Mutex
+	watchFailed bool
+	saveTimer   *time.Timer
+}
+
+// fileChange is a change by the watcher while rescanning, it's applied on top of the walk's result.
+type fileChange struct {
+	path    string
+	root    string
+	removed bool
+}
+
+type fileIndexCache struct {
+	Version int
+	Roots   []string
+	Options string
+	Files   map[string]string
+}
+
+var (
+	fileIndexesMu sync.Mutex
+	fileIndexes   = make(map[string]*fileIndex)
+)
+
+// sharedFileIndex returns the index for the roots and options, loading it from the cache if it's new. The finder and
+// content search share indexes, so files are only walked once. Temporary indexes, f.e. for explicit roots, are
+// dropped by dropTemporaryFileIndexes.
+func sharedFileIndex(roots []string, options fileWalkOptions, temporary bool) (*fileIndex, bool) {
+	fileIndexesMu.Lock()
+	defer fileIndexesMu.Unlock()
+
+	key := indexKey(roots, options)
+
+	if val, ok := fileIndexes[key]; ok {
+		return val, false
+	}
+
+	index := newFileIndex(roots, options)
+	index.temporary = temporary
+
+	if !temporary {
+		index.load()
+	}
+
+	fileIndexes[key] = index
+
+	return index, true
+}
+
+// dropTemporaryFileIndexes drops the indexes of per-invocation roots. They aren't persisted, as every set of roots
+// would leave a cache file behind.
+func dropTemporaryFileIndexes() {
+	fileIndexesMu.Lock()
+	defer fileIndexesMu.Unlock()
+
+	for k, v := range fileIndexes {
+		if v.temporary {
+			delete(fileIndexes, k)
+		}
+	}
+}
+
+// newFileIndex creates an index for the given roots. Every combination of roots and options gets its own cache file,
+// so switching between them doesn't discard the other indexes.
+func newFileIndex(roots []string, options fileWalkOptions) *fileIndex {
+	return &fileIndex{
+		roots:     roots,
+		options:   options,
+		cacheFile: filepath.Join(util.CacheDir(), fmt.Sprintf("finder_%x.gob", fileIndexHash(roots, options))),
+		files:     make(map[string]string),
+	}
+}
+
+func fileIndexHash(roots []string, options fileWalkOptions) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(roots, "\x00")))
+	h.Write([]byte(options.key()))
+
+	return h.Sum64()
+}
+
+// load reads the index from the cache. It's discarded if it was built with different settings.
+func (i *fileIndex) load() bool {
+	cache := fileIndexCache{}
+
+	if !util.FromGob(i.cacheFile, &cache) {
+		return false
+	}
+
+	if cache.Version != finderIndexVersion || cache.Options != i.options.key() || !slices.Equal(cache.Roots, i.roots) {
+		return false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.files = cache.Files
+	i.dirty = true
+	i.complete = true
+
+	return true
+}
+
+func (i *fileIndex) save() {
+	if i.temporary {
+		return
+	}
+
+	i.mu.Lock()
+	cache := fileIndexCache{
+		Version: finderIndexVersion,
+		Roots:   i.roots,
+		Options: i.options.key(),
+		Files:   make(map[string]string, len(i.files)),
+	}
+
+	for k, v := range i.files {
+		cache.Files[k] = v
+	}
+	i.mu.Unlock()
+
+	util.ToGob(&cache, i.cacheFile)
+}
+
+// scheduleSave debounces saving while the watcher applies changes.
+func (i *fileIndex) scheduleSave() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.saveTimer != nil {
+		i.saveTimer.Stop()
+	}
+
+	i.saveTimer = time.AfterFunc(5*time.Second, i.save)
+}
+
+// entries returns a copy of the current snapshot, so callers are free to modify it.
+func (i *fileIndex) entries() []util.Entry {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.dirty {
+		snapshot := make([]util.Entry, 0, len(i.files))
+
+		for path, root := range i.files {
+			snapshot = append(snapshot, fileEntry(path, root, len(i.roots) > 1))
+		}
+
+		i.snapshot = snapshot
+		i.dirty = false
+	}
+
+	return slices.Clone(i.snapshot)
+}
+
+// paths returns the indexed paths, sorted, and their roots. It reports false while the index is built for the first
+// time, as files are still missing then.
+func (i *fileIndex) paths() ([]string, map[string]string, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if !i.complete {
+		return nil, nil, false
+	}
+
+	roots := make(map[string]string, len(i.files))
+	paths := make([]string, 0, len(i.files))
+
+	for k, v := range i.files {
+		roots[k] = v
+		paths = append(paths, k)
+	}
+
+	slices.Sort(paths)
+
+	return paths, roots, true
+}
+
+func (i *fileIndex) isWatched() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.watcher != nil
+}
+
+func (i *fileIndex) add(path, root string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.rescanning {
+		i.changes = append(i.changes, fileChange{path: path, root: root})
+	}
+
+	if _, ok := i.files[path]; ok {
+		return
+	}
+
+	i.files[path] = root
+	i.dirty = true
+}
+
+// remove deletes the path and, in case it was a directory, everything below it.
+func (i *fileIndex) remove(path string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.rescanning {
+		i.changes = append(i.changes, fileChange{path: path, removed: true})
+	}
+
+	if removePath(i.files, path) {
+		i.dirty = true
+	}
+}
+
+// removePath deletes path and everything below it from files. It reports if anything was deleted.
+func removePath(files map[string]string, path string) bool {
+	prefix := fmt.Sprintf("%s%c", path, filepath.Separator)
+	removed := false
+
+	for k := range files {
+		if k == path || strings.HasPrefix(k, prefix) {
+			delete(files, k)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+// walk rebuilds the index from the filesystem. If there is no index yet, files are added as they are found,
+// otherwise the index is replaced once walking is done, so removed files disappear as well. Changes by the watcher
+// in the meantime are applied on top, as the walk may have passed their directories already.
+func (i *fileIndex) walk(ctx context.Context) {
+	i.mu.Lock()
+	if i.isWalking {
+		i.mu.Unlock()
+		return
+	}
+
+	i.isWalking = true
+	isInitial := len(i.files) == 0
+	i.rescanning = !isInitial
+	i.mu.Unlock()
+
+	defer func() {
+		i.mu.Lock()
+		i.isWalking = false
+		i.rescanning = false
+		i.changes = nil
+		i.mu.Unlock()
+	}()
+
+	found := make(map[string]string)
+
+	for _, root := range i.roots {
+		ok := walkFiles(ctx, root, i.options, func(path string) {
+			if isInitial {
+				i.add(path, root)
+				return
+			}
+
+			found[path] = root
+		})
+
+		if !ok {
+			return
+		}
+	}
+
+	i.mu.Lock()
+	if !isInitial {
+		for _, v := range i.changes {
+			if v.removed {
+				removePath(found, v.path)
+			} else {
+				found[v.path] = v.root
+			}
+		}
+
+		i.files = found
+		i.dirty = true
+	}
+
+	i.complete = true
+	i.mu.Unlock()
+
+	i.save()
+
+	i.mu.Lock()
+	isWatching := i.watcher != nil
+	i.mu.Unlock()
+
+	if isWatching {
+		i.watchDirs()
+	}
+}
+
+// watch keeps the index current by watching all indexed directories. Only used in service mode.
+func (i *fileIndex) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	i.mu.Lock()
+	i.watcher = watcher
+	i.mu.Unlock()
+
+	i.watchDirs()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			i.handleEvent(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Println(err)
+		}
+	}
+}
+
+// watchDirs adds a watch for every root and every directory containing indexed files.
+func (i *fileIndex) watchDirs() {
+	i.mu.Lock()
+	dirs := make(map[string]struct{})
+
+	for _, v := range i.roots {
+		dirs[v] = struct{}{}
+	}
+
+	for path, root := range i.files {
+		dir := filepath.Dir(path)
+
+		// in dirs-only mode the indexed directories themselves have to be watched as well
+		if i.options.DirsOnly {
+			dir = path
+		}
+
+		for ; strings.HasPrefix(dir, root) && dir != root; dir = filepath.Dir(dir) {
+			if _, ok := dirs[dir]; ok {
+				break
+			}
+
+			dirs[dir] = struct{}{}
+		}
+	}
+	i.mu.Unlock()
+
+	for dir := range dirs {
+		i.watchDir(dir)
+	}
+}
+
+func (i *fileIndex) watchDir(dir string) {
+	i.watchMu.Lock()
+	defer i.watchMu.Unlock()
+
+	if i.watchFailed {
+		return
+	}
+
+	err := i.watcher.Add(dir)
+	if err != nil && !os.IsNotExist(err) {
+		// most likely the inotify watch limit, don't flood the log
+		log.Printf("finder: can't watch %s: %s. Index will only be updated partially.", dir, err)
+		i.watchFailed = true
+	}
+}
+
+// handleEvent applies a single change. New paths are checked against the same rules as walking, so the index stays
+// identical to a full walk.
+func (i *fileIndex) handleEvent(event fsnotify.Event) {
+	root := i.rootFor(event.Name)
+	if root == "" {
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return
+		}
+
+		walker := newFileWalker(root, i.options, func(path string) {
+			i.add(path, root)
+
+			if i.options.DirsOnly {
+				i.watchDir(path)
+			} else {
+				i.watchDir(filepath.Dir(path))
+			}
+		})
+
+		if !walker.check(event.Name, info.IsDir()) {
+			return
+		}
+
+		walker.walkPath(context.Background(), event.Name, info.IsDir())
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		i.remove(event.Name)
+	default:
+		return
+	}
+
+	i.scheduleSave()
+}
+
+func (i *fileIndex) rootFor(path string) string {
+	for _, v := range i.roots {
+		if strings.HasPrefix(path, fmt.Sprintf("%s%c", v, filepath.Separator)) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// fileEntry creates the entry for path. If there are multiple roots, the root is shown as well.
+func fileEntry(path, root string, showRoot bool) util.Entry {
+	sub := "finder"
+
+	if showRoot {
+		sub = tildePath(root)
+	}
+
+	return util.Entry{
+		Label:            strings.TrimPrefix(strings.TrimPrefix(path, root), "/"),
+		Sub:              sub,
+		Exec:             fmt.Sprintf("xdg-open %s", util.ShellQuote(path)),
+		RecalculateScore: true,
+		DragDrop:         true,
+		DragDropData:     path,
+		Categories:       []string{"finder", "fzf"},
+		Class:            "finder",
+		Matching:         util.Fuzzy,
+		ActionsFunc: func() []util.Entry {
+			return fileActions(path)
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package modules
+
+import (
+	"context"
+
+	"github.com/abenz1267/walker/internal/config"
+	"github.com/abenz1267/walker/internal/util"
+)
+
+const MenuName = "menu"
+
+// Menu is a transient module showing a fixed list of entries, f.e. the actions of the selected entry.
+// It isn't configurable and only created by the UI.
+type Menu struct {
+	general config.GeneralModule
+	entries []util.Entry
+}
+
+func NewMenu(placeholder string, entries []util.Entry) *Menu {
+	m := &Menu{
+		general: config.GeneralModule{
+			Name:              MenuName,
+			Placeholder:       placeholder,
+			KeepSort:          true,
+			ShowSubWhenSingle: true,
+			IsSetup:           true,
+			HasInitialSetup:   true,
+		},
+		entries: make([]util.Entry, len(entries)),
+	}
+
+	for k, v := range entries {
+		v.Module = MenuName
+		v.RecalculateScore = true
+		v.Matching = util.Fuzzy
+
+		m.entries[k] = v
+	}
+
+	return m
+}
+
+func (m *Menu) General() *config.GeneralModule {
+	return &m.general
+}
+
+func (m *Menu) Cleanup() {}
+
+func (m *Menu) Refresh() {}
+
+func (m *Menu) Setup(cfg *config.Config) bool {
+	return true
+}
+
+func (m *Menu) SetupData(cfg *config.Config, ctx context.Context) {}
+
+func (m *Menu) Entries(ctx context.Context, term string) []util.Entry {
+	return m.entries
+}
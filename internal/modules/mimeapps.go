@@ -0,0 +1,163 @@
+package modules
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/abenz1267/walker/internal/util"
+	"github.com/adrg/xdg"
+)
+
+// mimeAppsFiles returns the mimeapps.list files, highest precedence first, see the MIME Applications Associations
+// specification.
+func mimeAppsFiles() []string {
+	desktops := []string{}
+
+	for _, v := range currentDesktops() {
+		desktops = append(desktops, strings.ToLower(v))
+	}
+
+	dirs := []string{xdg.ConfigHome}
+	dirs = append(dirs, xdg.ConfigDirs...)
+
+	for _, v := range append([]string{xdg.DataHome}, xdg.DataDirs...) {
+		dirs = append(dirs, filepath.Join(v, "applications"))
+	}
+
+	res := []string{}
+
+	for _, dir := range dirs {
+		for _, v := range desktops {
+			res = append(res, filepath.Join(dir, fmt.Sprintf("%s-mimeapps.list", v)))
+		}
+
+		res = append(res, filepath.Join(dir, "mimeapps.list"))
+	}
+
+	return res
+}
+
+// targetMimeType returns the mime type of a file, or the scheme handler type for URIs.
+func targetMimeType(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && u.Scheme != "file" {
+		return fmt.Sprintf("x-scheme-handler/%s", strings.ToLower(u.Scheme))
+	}
+
+	return fileMimeType(execTarget(target, false))
+}
+
+// appsForMime returns the applications able to open the mime type and the ID of the default one. The default
+// application comes first, followed by the added associations and all other applications listing the mime type,
+// sorted by name. Removed associations are left out.
+func appsForMime(mimeType string) ([]desktopApp, string) {
+	apps := desktopApps()
+
+	byID := make(map[string]desktopApp, len(apps))
+
+	for _, v := range apps {
+		byID[v.ID] = v
+	}
+
+	res := []desktopApp{}
+	seen := make(map[string]struct{})
+	removed := make(map[string]struct{})
+	defaultID := ""
+
+	isUsable := func(id string) bool {
+		_, exists := byID[id]
+		_, isRemoved := removed[id]
+
+		return exists && !isRemoved
+	}
+
+	isNew := func(id string) bool {
+		_, isSeen := seen[id]
+		return isUsable(id) && !isSeen
+	}
+
+	for _, file := range mimeAppsFiles() {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+
+		groups, err := parseKeyFile(f)
+		f.Close()
+
+		if err != nil {
+			continue
+		}
+
+		if defaultID == "" {
+			for _, id := range groups["Default Applications"].strings(mimeType) {
+				if isUsable(id) {
+					defaultID = id
+					break
+				}
+			}
+		}
+
+		for _, id := range groups["Added Associations"].strings(mimeType) {
+			if isNew(id) {
+				res = append(res, byID[id])
+				seen[id] = struct{}{}
+			}
+		}
+
+		// removals only apply to files with lower precedence
+		for _, id := range groups["Removed Associations"].strings(mimeType) {
+			removed[id] = struct{}{}
+		}
+	}
+
+	others := []desktopApp{}
+
+	for _, v := range apps {
+		if v.handles(mimeType) && isNew(v.ID) {
+			others = append(others, v)
+		}
+	}
+
+	slices.SortFunc(others, func(a, b desktopApp) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	res = append(res, others...)
+
+	if defaultID != "" {
+		res = slices.DeleteFunc(res, func(a desktopApp) bool { return a.ID == defaultID })
+		res = append([]desktopApp{byID[defaultID]}, res...)
+	}
+
+	return res, defaultID
+}
+
+// OpenWithEntries returns an entry per application able to open the file or URI, the default application first.
+func OpenWithEntries(target string) []util.Entry {
+	res := []util.Entry{}
+
+	apps, defaultID := appsForMime(targetMimeType(target))
+
+	for _, v := range apps {
+		sub := v.ID
+
+		if v.ID == defaultID {
+			sub = fmt.Sprintf("%s (default)", v.ID)
+		}
+
+		res = append(res, util.Entry{
+			Label:    fmt.Sprintf("Open with %s", v.Name),
+			Sub:      sub,
+			Icon:     v.Icon,
+			Exec:     v.command(target),
+			Terminal: v.Terminal,
+			Path:     v.Path,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,180 @@
+package modules
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abenz1267/walker/internal/config"
+	"github.com/abenz1267/walker/internal/util"
+	"github.com/adrg/xdg"
+)
+
+// Recent lists the recently used files recorded by applications in 'recently-used.xbel'.
+type Recent struct {
+	general config.GeneralModule
+	entries []util.Entry
+}
+
+type xbel struct {
+	Bookmarks []xbelBookmark `xml:"bookmark"`
+}
+
+type xbelBookmark struct {
+	Href         string            `xml:"href,attr"`
+	Modified     string            `xml:"modified,attr"`
+	Visited      string            `xml:"visited,attr"`
+	MimeType     xbelMimeType      `xml:"info>metadata>mime-type"`
+	Applications []xbelApplication `xml:"info>metadata>applications>application"`
+}
+
+type xbelMimeType struct {
+	Type string `xml:"type,attr"`
+}
+
+type xbelApplication struct {
+	Name     string `xml:"name,attr"`
+	Modified string `xml:"modified,attr"`
+}
+
+type recentFile struct {
+	path     string
+	mimeType string
+	app      string
+	used     time.Time
+}
+
+func (r *Recent) General() *config.GeneralModule {
+	return &r.general
+}
+
+func (r *Recent) Cleanup() {}
+
+func (r *Recent) Refresh() {
+	r.general.IsSetup = !r.general.Refresh
+}
+
+func (r *Recent) Entries(ctx context.Context, term string) []util.Entry {
+	return r.entries
+}
+
+func (r *Recent) Setup(cfg *config.Config) bool {
+	r.general = cfg.Builtins.Recent.GeneralModule
+
+	return true
+}
+
+func (r *Recent) SetupData(cfg *config.Config, ctx context.Context) {
+	files := readRecentFiles(filepath.Join(xdg.DataHome, "recently-used.xbel"))
+
+	entries := []util.Entry{}
+
+	for _, v := range files {
+		entries = append(entries, recentEntry(v))
+	}
+
+	r.entries = entries
+
+	r.general.IsSetup = true
+	r.general.HasInitialSetup = true
+}
+
+// readRecentFiles parses the xbel file and returns the local files that still exist, most recently used first.
+func readRecentFiles(file string) []recentFile {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return []recentFile{}
+	}
+
+	data := xbel{}
+
+	err = xml.Unmarshal(b, &data)
+	if err != nil {
+		return []recentFile{}
+	}
+
+	res := []recentFile{}
+
+	for _, v := range data.Bookmarks {
+		u, err := url.Parse(v.Href)
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+
+		if !util.FileExists(u.Path) {
+			continue
+		}
+
+		f := recentFile{
+			path:     u.Path,
+			mimeType: v.MimeType.Type,
+		}
+
+		for _, t := range []string{v.Modified, v.Visited} {
+			if used, err := time.Parse(time.RFC3339, t); err == nil && used.After(f.used) {
+				f.used = used
+			}
+		}
+
+		var appUsed time.Time
+
+		for _, a := range v.Applications {
+			used, _ := time.Parse(time.RFC3339, a.Modified)
+
+			if f.app == "" || used.After(appUsed) {
+				f.app = a.Name
+				appUsed = used
+			}
+		}
+
+		res = append(res, f)
+	}
+
+	slices.SortStableFunc(res, func(a, b recentFile) int {
+		return b.used.Compare(a.used)
+	})
+
+	return res
+}
+
+func recentEntry(f recentFile) util.Entry {
+	sub := []string{}
+
+	if f.app != "" {
+		sub = append(sub, f.app)
+	}
+
+	if !f.used.IsZero() {
+		sub = append(sub, f.used.Local().Format("2006-01-02 15:04"))
+	}
+
+	mimeType := f.mimeType
+
+	if mimeType == "" {
+		mimeType = fileMimeType(f.path)
+	}
+
+	path := f.path
+
+	return util.Entry{
+		Label:            tildePath(f.path),
+		Sub:              strings.Join(sub, " · "),
+		Exec:             fmt.Sprintf("xdg-open %s", util.ShellQuote(f.path)),
+		Icon:             mimeIcon(mimeType),
+		RecalculateScore: true,
+		DragDrop:         true,
+		DragDropData:     f.path,
+		Categories:       []string{"recent", "fzf"},
+		Class:            "recent",
+		Matching:         util.Fuzzy,
+		ActionsFunc: func() []util.Entry {
+			return fileActions(path)
+		},
+	}
+}
@@ -4,15 +4,14 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"io/fs"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/abenz1267/walker/internal/config"
+	"github.com/abenz1267/walker/internal/history"
 	"github.com/abenz1267/walker/internal/util"
 )
 
@@ -22,9 +21,20 @@ type Runner struct {
 	genericEntry bool
 	aliases      map[string]string
 	bins         []string
+	config       config.Runner
+	shell        string
+	shellFlag    string
+	shellNames   map[string]struct{}
+	functions    []string
+	sshConfig    config.SSH
+	sshHosts     []string
+	mu           sync.RWMutex
+	executables  map[string]map[string]struct{}
+	isWatching   bool
+	cwd          string
 }
 
-func (r Runner) Cleanup() {}
+func (r *Runner) Cleanup() {}
 
 func (r *Runner) General() *config.GeneralModule {
 	return &r.general
@@ -34,70 +44,140 @@ func (r *Runner) Setup(cfg *config.Config) bool {
 	r.general = cfg.Builtins.Runner.GeneralModule
 	r.shellConfig = cfg.Builtins.Runner.ShellConfig
 	r.genericEntry = cfg.Builtins.Runner.GenericEntry
+	r.config = cfg.Builtins.Runner
+	r.sshConfig = cfg.Builtins.SSH
+
+	switch r.config.CompletionEngine {
+	case "", "fish", "zsh":
+	default:
+		log.Printf("runner: unknown completion engine '%s', use 'fish' or 'zsh'", r.config.CompletionEngine)
+		r.config.CompletionEngine = ""
+	}
 
 	return true
 }
 
 func (r *Runner) SetupData(cfg *config.Config, ctx context.Context) {
+	// the watcher merges aliases and functions into the binaries
+	r.mu.Lock()
 	r.parseAliases()
+	r.importShell()
+	r.mu.Unlock()
 
-	if len(cfg.Builtins.Runner.Includes) > 0 {
-		r.bins = cfg.Builtins.Runner.Includes
-	} else {
-		r.getBins()
-	}
+	r.sshHosts = []string{}
 
-	filtered := []string{}
+	for _, v := range loadSSHHosts(r.sshConfig) {
+		r.sshHosts = append(r.sshHosts, v.Alias)
+	}
 
-	if len(cfg.Builtins.Runner.Excludes) > 0 {
-		for _, v := range r.bins {
-			if !slices.Contains(cfg.Builtins.Runner.Excludes, v) {
-				filtered = append(filtered, v)
-			}
+	if len(r.config.Includes) == 0 && !r.isWatching {
+		if cfg.IsService {
+			r.watchPath()
+		} else {
+			r.scanPath()
 		}
-
-		r.bins = filtered
 	}
 
+	r.updateBins()
+
 	r.general.IsSetup = true
 	r.general.HasInitialSetup = true
 }
 
+// SetCwd sets the working directory of the client, relative paths are completed against it.
+func (r *Runner) SetCwd(cwd string) {
+	r.mu.Lock()
+	r.cwd = cwd
+	r.mu.Unlock()
+}
+
 func (r *Runner) Refresh() {
 	r.general.IsSetup = !r.general.Refresh
 }
 
-func (r Runner) Entries(ctx context.Context, term string) []util.Entry {
+// InitialEntries returns the binaries that were run before, so the most used ones can be shown right away.
+func (r *Runner) InitialEntries(ctx context.Context) []util.Entry {
+	used := make(map[string]struct{})
+
+	for _, v := range history.GetInputHistory(r.general.Name) {
+		used[v.Identifier] = struct{}{}
+	}
+
+	entries := []util.Entry{}
+
+	if len(used) == 0 {
+		return entries
+	}
+
+	sh := r.shellState()
+
+	for _, v := range r.binList() {
+		bin := v
+
+		if val, ok := sh.aliases[v]; ok {
+			bin = val
+		}
+
+		exec := bin
+
+		if sh.inShell(v) {
+			exec = sh.exec(v, nil)
+		}
+
+		entry := binEntry(bin, v, exec)
+
+		if _, ok := used[entry.Identifier()]; ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func binEntry(bin, name, exec string) util.Entry {
+	e := util.Entry{
+		Label:            bin,
+		Searchable:       name,
+		Sub:              "Runner",
+		Exec:             exec,
+		Class:            "runner",
+		History:          true,
+		RecalculateScore: true,
+		MatchFields:      1,
+		Matching:         util.Fuzzy,
+	}
+
+	e.ActionsFunc = func() []util.Entry {
+		return []util.Entry{outputAction(e)}
+	}
+
+	return e
+}
+
+func (r *Runner) Entries(ctx context.Context, term string) []util.Entry {
 	entries := []util.Entry{}
 
 	fields := strings.Fields(term)
+	sh := r.shellState()
 
-	for _, v := range r.bins {
+	for _, v := range r.binList() {
 		bin := v
 
-		if val, ok := r.aliases[v]; ok {
+		if val, ok := sh.aliases[v]; ok {
 			bin = val
 		}
 
 		exec := term
 
 		if len(fields) > 0 {
-			exec = fmt.Sprintf("%s %s", bin, strings.Join(fields[1:], " "))
-		}
-
-		n := util.Entry{
-			Label:            bin,
-			Searchable:       v,
-			Sub:              "Runner",
-			Exec:             exec,
-			Class:            "runner",
-			History:          true,
-			RecalculateScore: true,
-			MatchFields:      1,
-			Matching:         util.Fuzzy,
+			if sh.inShell(v) {
+				exec = sh.exec(v, fields[1:])
+			} else {
+				exec = fmt.Sprintf("%s %s", bin, strings.Join(fields[1:], " "))
+			}
 		}
 
-		entries = append(entries, n)
+		entries = append(entries, binEntry(bin, v, exec))
 	}
 
 	exec := term
@@ -105,11 +185,15 @@ func (r Runner) Entries(ctx context.Context, term string) []util.Entry {
 	if len(fields) > 0 {
 		bin := fields[0]
 
-		if val, ok := r.aliases[bin]; ok {
+		if val, ok := sh.aliases[bin]; ok {
 			bin = val
 		}
 
 		exec = fmt.Sprintf("%s %s", bin, strings.Join(fields[1:], " "))
+
+		if sh.inShell(fields[0]) {
+			exec = sh.exec(fields[0], fields[1:])
+		}
 	}
 
 	if r.genericEntry {
@@ -124,80 +208,92 @@ func (r Runner) Entries(ctx context.Context, term string) []util.Entry {
 			Matching:         util.Fuzzy,
 		}
 
+		n.Actions = []util.Entry{outputAction(n)}
+
 		entries = append(entries, n)
 	}
 
 	return entries
 }
 
-func (r *Runner) getBins() {
-	path := os.Getenv("PATH")
-
-	paths := strings.Split(path, ":")
-
-	bins := []string{}
-
-	for _, p := range paths {
-		filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
-			if d != nil && d.IsDir() {
-				return nil
-			}
-
-			exec, _ := exec.LookPath(filepath.Base(path))
-			if exec == "" {
-				return nil
-			}
-
-			bins = append(bins, filepath.Base(path))
+func (r *Runner) parseAliases() {
+	r.aliases = make(map[string]string)
 
-			return nil
-		})
+	if r.shellConfig == "" {
+		return
 	}
 
-	for k := range r.aliases {
-		bins = append(bins, k)
+	file, err := os.Open(r.shellConfig)
+	if err != nil {
+		log.Println(err)
+		return
 	}
 
-	slices.Sort(bins)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
 
-	j := 0
-	for i := 1; i < len(bins); i++ {
-		if bins[j] == bins[i] {
-			continue
+	for scanner.Scan() {
+		if name, val, ok := parseAliasLine(scanner.Text()); ok {
+			r.aliases[name] = val
 		}
-		j++
-		bins[j] = bins[i]
 	}
-
-	bins = bins[:j+1]
-
-	r.bins = bins
 }
 
-func (r *Runner) parseAliases() {
-	if r.shellConfig == "" {
+// importShell adds the aliases and functions defined in the configuration of the shell. They are run through the
+// shell, so functions and aliases referencing other aliases work.
+func (r *Runner) importShell() {
+	r.shell = ""
+	r.shellNames = make(map[string]struct{})
+	r.functions = []string{}
+
+	if !r.config.ShellAliases {
 		return
 	}
 
-	r.aliases = make(map[string]string)
-
-	file, err := os.Open(r.shellConfig)
-	if err != nil {
-		log.Println(err)
+	shell, dialect, ok := resolveShell(r.config.Shell)
+	if !ok {
 		return
 	}
 
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
+	defs := loadShellDefinitions(shell, dialect, time.Duration(r.config.ShellTimeout)*time.Millisecond)
 
-	for scanner.Scan() {
-		text := scanner.Text()
+	r.shell = shell
+	r.shellFlag = dialect.flag
 
-		if strings.HasPrefix(text, "alias") {
-			splits := strings.SplitN(text, "=", 2)
-			alias := strings.TrimPrefix(splits[0], "alias ")
+	for k, v := range defs.Aliases {
+		r.aliases[k] = v
+		r.shellNames[k] = struct{}{}
+	}
 
-			r.aliases[alias] = strings.TrimSuffix(strings.TrimPrefix(splits[1], "\""), "\"")
-		}
+	for _, v := range defs.Functions {
+		r.functions = append(r.functions, v)
+		r.shellNames[v] = struct{}{}
 	}
 }
+
+// runnerShell holds the aliases and shell functions. SetupData replaces the maps under the lock, never modifies them
+// afterwards, so a snapshot can be used without locking.
+type runnerShell struct {
+	aliases map[string]string
+	names   map[string]struct{}
+	shell   string
+	flag    string
+}
+
+func (r *Runner) shellState() runnerShell {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return runnerShell{aliases: r.aliases, names: r.shellNames, shell: r.shell, flag: r.shellFlag}
+}
+
+func (s runnerShell) inShell(name string) bool {
+	_, ok := s.names[name]
+	return ok
+}
+
+func (s runnerShell) exec(name string, args []string) string {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+
+	return fmt.Sprintf("%s %s %s", util.ShellQuote(s.shell), s.flag, util.ShellQuote(cmd))
+}
@@ -0,0 +1,359 @@
+package modules
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abenz1267/walker/internal/util"
+)
+
+// sshCommands take a host as argument.
+var sshCommands = []string{"ssh", "mosh", "sftp", "ssh-copy-id"}
+
+// Complete completes the argument being typed: hosts for ssh and friends, paths otherwise. If a completion engine is
+// configured, it's asked first. It returns the whole completed input, or an empty string if there's nothing to add.
+func (r *Runner) Complete(ctx context.Context, term string) string {
+	idx := lastWordStart(term) - 1
+
+	if idx < 0 || strings.TrimSpace(term[:idx]) == "" {
+		return ""
+	}
+
+	head, word := term[:idx+1], term[idx+1:]
+
+	cmd := strings.Fields(term)[0]
+
+	if val, ok := r.shellState().aliases[cmd]; ok {
+		if fields := strings.Fields(val); len(fields) > 0 {
+			cmd = fields[0]
+		}
+	}
+
+	candidates := []string{}
+
+	r.mu.RLock()
+	cwd := r.cwd
+	r.mu.RUnlock()
+
+	switch r.config.CompletionEngine {
+	case "fish":
+		candidates = fishCompletions(ctx, term, cwd)
+	case "zsh":
+		candidates = zshCompletions(ctx, term, cwd)
+	}
+
+	if len(candidates) == 0 {
+		if slices.Contains(sshCommands, cmd) && !strings.Contains(word, "/") {
+			candidates = r.hostCompletions(word)
+		} else {
+			candidates = pathCompletions(word, cwd)
+		}
+	}
+
+	completed := commonPrefix(candidates)
+
+	if len(completed) <= len(word) || !strings.HasPrefix(completed, word) {
+		return ""
+	}
+
+	return head + completed
+}
+
+// lastWordStart returns the index the last word starts at, spaces escaped with a backslash don't separate words.
+func lastWordStart(term string) int {
+	start := 0
+
+	for i := 0; i < len(term); i++ {
+		switch term[i] {
+		case '\\':
+			i++
+		case ' ', '\t':
+			start = i + 1
+		}
+	}
+
+	return start
+}
+
+func (r *Runner) hostCompletions(word string) []string {
+	user, host, hasUser := strings.Cut(word, "@")
+
+	if !hasUser {
+		host = word
+	}
+
+	res := []string{}
+
+	for _, v := range r.sshHosts {
+		if strings.HasPrefix(v, host) {
+			if hasUser {
+				v = user + "@" + v
+			}
+
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// pathCompletions returns the files matching the partial path. Relative paths are resolved against cwd, the working
+// directory of the client, falling back to the own one and the home directory. Hidden files are only completed if the name starts with a dot.
+func pathCompletions(word, cwd string) []string {
+	dir, base := filepath.Split(unescapeWord(word))
+	typedDir, _ := filepath.Split(word)
+
+	home, _ := os.UserHomeDir()
+
+	resolved := dir
+
+	switch {
+	case dir == "~/" || strings.HasPrefix(dir, "~/"):
+		resolved = filepath.Join(home, dir[2:])
+	case dir == "":
+		resolved = "."
+	}
+
+	if !filepath.IsAbs(resolved) {
+		if cwd == "" {
+			wd, err := os.Getwd()
+			if err != nil {
+				wd = home
+			}
+
+			cwd = wd
+		}
+
+		resolved = filepath.Join(cwd, resolved)
+	}
+
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return nil
+	}
+
+	res := []string{}
+
+	for _, v := range entries {
+		name := v.Name()
+
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+
+		candidate := typedDir + escapeWord(name)
+
+		if info, err := os.Stat(filepath.Join(resolved, name)); err == nil && info.IsDir() {
+			candidate += "/"
+		}
+
+		res = append(res, candidate)
+	}
+
+	return res
+}
+
+// fishCompletions asks fish for the completions of the last word, run in cwd. Candidates are the whole completed
+// words.
+func fishCompletions(ctx context.Context, term, cwd string) []string {
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "fish", "-c", "complete --do-complete=$argv[1]", term)
+	cmd.Dir = cwd
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	res := []string{}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		candidate, _, _ := strings.Cut(line, "\t")
+
+		if candidate != "" {
+			res = append(res, escapeWord(candidate))
+		}
+	}
+
+	return res
+}
+
+func commonPrefix(candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	res := candidates[0]
+
+	for _, v := range candidates[1:] {
+		for !strings.HasPrefix(v, res) {
+			// trim whole runes, so multi-byte characters aren't cut in half
+			_, size := utf8.DecodeLastRuneInString(res)
+			res = res[:len(res)-size]
+		}
+	}
+
+	return res
+}
+
+// escapeWord escapes characters with a special meaning to the shell with backslashes.
+func escapeWord(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if !shellSafe.MatchString(s[i:i+1]) && s[i] != '~' && s[i] < 0x80 {
+			b.WriteByte('\\')
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+func unescapeWord(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i < len(s)-1 {
+			i++
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// zshCompletions asks zsh for the completions of the last word, run in cwd. zsh only completes in interactive
+// shells, so one is started in a pseudo terminal, with compadd replaced to print the matches instead of inserting them.
+// The shell doesn't read the user's configuration, only the completion system is loaded. Candidates are the whole
+// completed words.
+func zshCompletions(ctx context.Context, term, cwd string) []string {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "zsh", "-f", "-c", zshCapture, "zsh", term)
+	cmd.Dir = cwd
+	cmd.Env = append(os.Environ(),
+		"WALKER_ZSH_SETUP="+zshCaptureSetup,
+		"WALKER_ZCOMPDUMP="+filepath.Join(util.CacheDir(), "zcompdump"),
+	)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	res := []string{}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		candidate := strings.TrimRight(line, "\r")
+
+		if candidate != "" {
+			res = append(res, escapeWord(candidate))
+		}
+	}
+
+	return res
+}
+
+// zshCapture types the term into an interactive zsh followed by a tab and prints the matches, which zshCaptureSetup
+// frames with null lines.
+const zshCapture = `
+zmodload zsh/zpty || exit 1
+
+zpty z zsh -f -i
+
+zpty -w z 'eval "$WALKER_ZSH_SETUP"'
+
+local line
+
+repeat 4; do
+	zpty -r z line
+	[[ $line == ok* ]] && break
+done
+
+[[ $line == ok* ]] || exit 2
+
+zpty -w z "$1"$'\t'
+
+integer framed=0
+
+while zpty -r z; do :; done | while IFS= read -r line; do
+	if [[ $line == *$'\0\r' ]]; then
+		(( framed++ )) && exit 0 || continue
+	fi
+
+	(( framed )) && print -r -- "$line"
+done
+
+exit 2
+`
+
+// zshCaptureSetup runs in the interactive zsh. Enter does nothing, tab completes and compadd prints the matches with
+// their prefixes and suffixes, directories get a trailing slash.
+const zshCaptureSetup = `
+PROMPT=
+
+autoload -U compinit
+compinit -d "$WALKER_ZCOMPDUMP"
+
+bindkey '^M' undefined
+bindkey '^J' undefined
+bindkey '^I' complete-word
+
+null-line() {
+	echo -E - $'\0'
+}
+
+compprefuncs=( null-line )
+comppostfuncs=( null-line exit )
+
+zstyle ':completion:*' list-grouped false
+zstyle ':completion:*' insert-tab false
+zstyle ':completion:*' list-separator ''
+
+zmodload zsh/zutil
+
+compadd() {
+	# calls only collecting matches are left alone
+	if [[ ${@[1,(i)(-|--)]} == *-(O|A|D)\ * ]]; then
+		builtin compadd "$@"
+		return $?
+	fi
+
+	typeset -a __hits
+	builtin compadd -A __hits "$@"
+
+	setopt localoptions norcexpandparam extendedglob
+
+	typeset -A apre hpre hsuf asuf
+	zparseopts -E P:=apre p:=hpre S:=asuf s:=hsuf
+
+	integer dirsuf=0
+
+	if [[ -z $hsuf && "${${@//-default-/}% -# *}" == *-[[:alnum:]]#f* ]]; then
+		dirsuf=1
+	fi
+
+	[[ -n $__hits ]] || return
+
+	local dsuf
+
+	for i in {1..$#__hits}; do
+		(( dirsuf )) && [[ -d $__hits[$i] ]] && dsuf=/ || dsuf=
+		print -r -- $IPREFIX$apre$hpre$__hits[$i]$dsuf$hsuf$asuf
+	done
+}
+
+echo ok
+`
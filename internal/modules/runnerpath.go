@@ -0,0 +1,188 @@
+package modules
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// pathDirs returns the directories in $PATH, without duplicates.
+func pathDirs() []string {
+	res := []string{}
+
+	for _, v := range filepath.SplitList(os.Getenv("PATH")) {
+		if v == "" {
+			continue
+		}
+
+		if v = filepath.Clean(v); !slices.Contains(res, v) {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// pathExecutables returns the names of the executables in dir. Like the shell, subdirectories aren't searched.
+func pathExecutables(dir string) map[string]struct{} {
+	res := make(map[string]struct{})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return res
+	}
+
+	for _, v := range entries {
+		if !v.IsDir() && isExecutable(filepath.Join(dir, v.Name())) {
+			res[v.Name()] = struct{}{}
+		}
+	}
+
+	return res
+}
+
+// isExecutable reports if path, or the file it links to, is a regular file with any executable bit set.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// scanPath reads the executables of all directories in $PATH.
+func (r *Runner) scanPath() {
+	executables := make(map[string]map[string]struct{})
+
+	for _, v := range pathDirs() {
+		executables[v] = pathExecutables(v)
+	}
+
+	r.mu.Lock()
+	r.executables = executables
+	r.mu.Unlock()
+}
+
+// watchPath keeps the executables current by watching the directories in $PATH. Only used in service mode.
+func (r *Runner) watchPath() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println(err)
+		r.scanPath()
+
+		return
+	}
+
+	r.isWatching = true
+
+	// watch before scanning, so nothing installed in between is missed
+	for _, v := range pathDirs() {
+		if err := watcher.Add(v); err != nil && !os.IsNotExist(err) {
+			log.Printf("runner: can't watch %s: %s", v, err)
+		}
+	}
+
+	r.scanPath()
+
+	go func() {
+		defer watcher.Close()
+
+		// package managers install many files at once, update the list only once they are done
+		var update <-chan time.Time
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if r.handlePathEvent(event) && update == nil {
+					update = time.After(200 * time.Millisecond)
+				}
+			case <-update:
+				update = nil
+				r.updateBins()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Println(err)
+			}
+		}
+	}()
+}
+
+// handlePathEvent checks the file of the event again and reports if the executables changed. Created, removed,
+// renamed and chmod-ed files are all handled the same.
+func (r *Runner) handlePathEvent(event fsnotify.Event) bool {
+	dir, name := filepath.Split(event.Name)
+	executable := isExecutable(event.Name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names, ok := r.executables[filepath.Clean(dir)]
+	if !ok {
+		return false
+	}
+
+	if _, exists := names[name]; exists == executable {
+		return false
+	}
+
+	if executable {
+		names[name] = struct{}{}
+	} else {
+		delete(names, name)
+	}
+
+	return true
+}
+
+// updateBins merges the executables, aliases and functions into the sorted list of binaries, or uses the
+// configured ones.
+func (r *Runner) updateBins() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bins := []string{}
+
+	if len(r.config.Includes) > 0 {
+		bins = append(bins, r.config.Includes...)
+	} else {
+		for _, names := range r.executables {
+			for k := range names {
+				bins = append(bins, k)
+			}
+		}
+
+		for k := range r.aliases {
+			bins = append(bins, k)
+		}
+
+		bins = append(bins, r.functions...)
+
+		slices.Sort(bins)
+		bins = slices.Compact(bins)
+	}
+
+	if len(r.config.Excludes) > 0 {
+		bins = slices.DeleteFunc(bins, func(v string) bool {
+			return slices.Contains(r.config.Excludes, v)
+		})
+	}
+
+	r.bins = bins
+}
+
+// binList returns the current binaries. The list is replaced, never modified, so it can be used without locking.
+func (r *Runner) binList() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.bins
+}
@@ -0,0 +1,320 @@
+package modules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/abenz1267/walker/internal/util"
+	"github.com/adrg/xdg"
+)
+
+const (
+	shellDefinitionsVersion = 1
+	shellMarker             = "__walker_functions__"
+)
+
+// shellDefinitions are the aliases and functions defined in the interactive configuration of a shell.
+type shellDefinitions struct {
+	Version   int
+	Key       string
+	Aliases   map[string]string
+	Functions []string
+}
+
+type shellDialect struct {
+	flag  string
+	query string
+}
+
+// shellDialects lists the supported shells with the flag to run commands with their interactive configuration and
+// the script printing aliases, a marker and function names.
+var shellDialects = map[string]shellDialect{
+	"bash": {
+		flag:  "-ic",
+		query: fmt.Sprintf("alias -p; echo %s; compgen -A function", shellMarker),
+	},
+	"zsh": {
+		flag:  "-ic",
+		query: fmt.Sprintf("alias -L; echo %s; print -l ${(k)functions}", shellMarker),
+	},
+	"fish": {
+		flag:  "-c",
+		query: fmt.Sprintf("alias; abbr --show; echo %s; functions --names", shellMarker),
+	},
+}
+
+// resolveShell returns the path of the configured shell, falling back to $SHELL, and its dialect.
+func resolveShell(shell string) (string, shellDialect, bool) {
+	if shell == "" {
+		shell = os.Getenv("SHELL")
+	}
+
+	if shell == "" {
+		return "", shellDialect{}, false
+	}
+
+	dialect, ok := shellDialects[filepath.Base(shell)]
+	if !ok {
+		log.Printf("runner: importing aliases from %s is not supported", shell)
+		return "", shellDialect{}, false
+	}
+
+	path, err := exec.LookPath(shell)
+	if err != nil {
+		log.Printf("runner: %s", err)
+		return "", shellDialect{}, false
+	}
+
+	return path, dialect, true
+}
+
+var shellQueryRunning atomic.Bool
+
+// loadShellDefinitions returns the definitions of the shell. They are cached until one of the shell's config files
+// changes. A stale cache is used while the shell is queried in the background, the shell is only waited for, up to
+// the timeout, if there is no cache at all.
+func loadShellDefinitions(shell string, dialect shellDialect, timeout time.Duration) shellDefinitions {
+	key := shellConfigKey(shell)
+	file := filepath.Join(util.CacheDir(), "runner_shell.gob")
+
+	cached := shellDefinitions{}
+
+	if util.FromGob(file, &cached) && cached.Version == shellDefinitionsVersion {
+		if cached.Key != key && shellQueryRunning.CompareAndSwap(false, true) {
+			go func() {
+				defer shellQueryRunning.Store(false)
+				queryAndCacheShell(shell, dialect, timeout, key, file)
+			}()
+		}
+
+		return cached
+	}
+
+	res, _ := queryAndCacheShell(shell, dialect, timeout, key, file)
+
+	return res
+}
+
+func queryAndCacheShell(shell string, dialect shellDialect, timeout time.Duration, key, file string) (shellDefinitions, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res, err := queryShell(ctx, shell, dialect)
+	if err != nil {
+		log.Printf("runner: querying %s: %s", shell, err)
+		return res, false
+	}
+
+	res.Version = shellDefinitionsVersion
+	res.Key = key
+
+	util.ToGob(&res, file)
+
+	return res, true
+}
+
+// queryShell starts the shell with its interactive configuration and parses the printed aliases and functions.
+// Anything printed by the configuration itself comes before the aliases and can't be told apart from them, so only
+// lines that parse as aliases are used.
+func queryShell(ctx context.Context, shell string, dialect shellDialect) (shellDefinitions, error) {
+	cmd := exec.CommandContext(ctx, shell, dialect.flag, dialect.query)
+	// background jobs started by the configuration might keep the output open
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	out, err := cmd.Output()
+	if err != nil && !bytes.Contains(out, []byte(shellMarker)) {
+		return shellDefinitions{}, err
+	}
+
+	res := shellDefinitions{
+		Aliases:   make(map[string]string),
+		Functions: []string{},
+	}
+
+	aliases, functions, _ := strings.Cut(string(out), shellMarker)
+
+	for _, line := range strings.Split(aliases, "\n") {
+		if name, val, ok := parseAliasLine(line); ok {
+			res.Aliases[name] = val
+		}
+	}
+
+	for _, name := range strings.FieldsFunc(functions, func(r rune) bool { return r == '\n' || r == ',' || r == ' ' }) {
+		// leading underscores and fish_ mark helpers and completions
+		if strings.HasPrefix(name, "_") || strings.HasPrefix(name, "fish_") {
+			continue
+		}
+
+		if _, ok := res.Aliases[name]; !ok {
+			res.Functions = append(res.Functions, name)
+		}
+	}
+
+	return res, nil
+}
+
+// shellConfigKey identifies the state of the shell's config files by their modification times.
+func shellConfigKey(shell string) string {
+	home, _ := os.UserHomeDir()
+
+	files := []string{}
+
+	switch filepath.Base(shell) {
+	case "bash":
+		for _, v := range []string{".bashrc", ".bash_aliases", ".bash_profile", ".profile"} {
+			files = append(files, filepath.Join(home, v))
+		}
+	case "zsh":
+		dir := os.Getenv("ZDOTDIR")
+
+		if dir == "" {
+			dir = home
+		}
+
+		for _, v := range []string{".zshenv", ".zprofile", ".zshrc"} {
+			files = append(files, filepath.Join(dir, v))
+		}
+	case "fish":
+		dir := filepath.Join(xdg.ConfigHome, "fish")
+
+		files = append(files, filepath.Join(dir, "config.fish"))
+
+		for _, sub := range []string{"conf.d", "functions"} {
+			files = append(files, filepath.Join(dir, sub))
+
+			entries, _ := os.ReadDir(filepath.Join(dir, sub))
+
+			for _, v := range entries {
+				files = append(files, filepath.Join(dir, sub, v.Name()))
+			}
+		}
+	}
+
+	var b strings.Builder
+
+	b.WriteString(shell)
+
+	for _, v := range files {
+		if info, err := os.Stat(v); err == nil {
+			fmt.Fprintf(&b, " %s:%d", v, info.ModTime().UnixNano())
+		}
+	}
+
+	return b.String()
+}
+
+// parseAliasLine parses alias definitions as printed by 'alias -p' in bash, 'alias -L' in zsh and 'alias' or
+// 'abbr --show' in fish, as well as the common forms in shell configs. Global and suffix aliases of zsh don't name
+// commands and are skipped.
+func parseAliasLine(line string) (string, string, bool) {
+	words := splitShellWords(strings.TrimSpace(line))
+
+	if len(words) < 2 || (words[0] != "alias" && words[0] != "abbr") {
+		return "", "", false
+	}
+
+	isAbbr := words[0] == "abbr"
+	words = words[1:]
+
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+		flag := words[0]
+		words = words[1:]
+
+		switch {
+		case flag == "--":
+		case flag == "-g" || flag == "-s" || flag == "--global" || flag == "--suffix":
+			return "", "", false
+		case isAbbr && (flag == "--position" || flag == "--regex" || flag == "--command" || flag == "--function"):
+			// flags with values
+			if len(words) > 0 {
+				words = words[1:]
+			}
+
+			continue
+		default:
+			continue
+		}
+
+		break
+	}
+
+	if len(words) == 0 {
+		return "", "", false
+	}
+
+	if name, val, ok := strings.Cut(words[0], "="); ok && !isAbbr {
+		return name, val, name != ""
+	}
+
+	if len(words) < 2 {
+		return "", "", false
+	}
+
+	return words[0], strings.Join(words[1:], " "), true
+}
+
+// splitShellWords splits a line into words like a POSIX shell, handling single quotes, double quotes and backslash
+// escapes. Quotes are removed and adjacent parts are joined, so "a='b c'" is a single word.
+func splitShellWords(line string) []string {
+	res := []string{}
+
+	var b strings.Builder
+
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == '\'':
+			inWord = true
+
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				b.WriteString(line[i+1:])
+				i = len(line)
+				continue
+			}
+
+			b.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i < len(line)-1 && strings.IndexByte("\"\\$`", line[i+1]) != -1 {
+					i++
+				}
+
+				b.WriteByte(line[i])
+			}
+		case c == '\\' && i < len(line)-1:
+			inWord = true
+			i++
+			b.WriteByte(line[i])
+		case c == ' ' || c == '\t':
+			if inWord {
+				res = append(res, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			inWord = true
+			b.WriteByte(c)
+		}
+	}
+
+	if inWord {
+		res = append(res, b.String())
+	}
+
+	return res
+}
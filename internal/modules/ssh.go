@@ -5,8 +5,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/abenz1267/walker/internal/config"
@@ -14,8 +17,89 @@ import (
 )
 
 type SSH struct {
-	general config.GeneralModule
-	entries []util.Entry
+	general   config.GeneralModule
+	entries   []util.Entry
+	hosts     []sshHost
+	actions   []config.SSHAction
+	altAction string
+}
+
+type sshBuiltinAction struct {
+	bin   string
+	entry func(h sshHost, user string) util.Entry
+}
+
+var sshBuiltinActions = map[string]sshBuiltinAction{
+	"mosh": {
+		bin: "mosh",
+		entry: func(h sshHost, user string) util.Entry {
+			cmd := fmt.Sprintf("mosh %s", h.target(user))
+
+			if p := h.explicitPort(); p != "" {
+				cmd = fmt.Sprintf("mosh --ssh='ssh -p %s' %s", p, h.target(user))
+			}
+
+			return util.Entry{Label: "Connect with mosh", Exec: cmd, Terminal: true}
+		},
+	},
+	"sftp": {
+		bin: "xdg-open",
+		entry: func(h sshHost, user string) util.Entry {
+			host := h.HostName
+			if host == "" {
+				host = h.Alias
+			}
+
+			// IPv6 literals have to be bracketed in URLs
+			if strings.Contains(host, ":") {
+				host = fmt.Sprintf("[%s]", host)
+			}
+
+			if user == "" {
+				user = h.User
+			}
+
+			if user != "" {
+				host = fmt.Sprintf("%s@%s", user, host)
+			}
+
+			if h.Port != "" && h.Port != "22" {
+				host = fmt.Sprintf("%s:%s", host, h.Port)
+			}
+
+			return util.Entry{
+				Label: "Open in file manager (sftp)",
+				Exec:  fmt.Sprintf("xdg-open %s", util.ShellQuote(fmt.Sprintf("sftp://%s/", host))),
+			}
+		},
+	},
+	"copy_id": {
+		bin: "ssh-copy-id",
+		entry: func(h sshHost, user string) util.Entry {
+			cmd := fmt.Sprintf("ssh-copy-id %s", h.target(user))
+
+			if p := h.explicitPort(); p != "" {
+				cmd = fmt.Sprintf("ssh-copy-id -p %s %s", p, h.target(user))
+			}
+
+			return util.Entry{Label: "Copy public key (ssh-copy-id)", Exec: cmd, Terminal: true}
+		},
+	},
+	"copy_host": {
+		bin: "wl-copy",
+		entry: func(h sshHost, user string) util.Entry {
+			host := h.HostName
+			if host == "" {
+				host = h.Alias
+			}
+
+			return util.Entry{
+				Label: "Copy host name",
+				Exec:  "wl-copy",
+				Piped: util.Piped{Content: host, Type: "string"},
+			}
+		},
+	},
 }
 
 func (s *SSH) General() *config.GeneralModule {
@@ -28,120 +112,343 @@ func (s *SSH) Refresh() {
 	s.general.IsSetup = !s.general.Refresh
 }
 
+// InitialEntries returns all hosts, so the most used ones can be shown right away.
+func (s SSH) InitialEntries(ctx context.Context) []util.Entry {
+	return s.entries
+}
+
 func (s SSH) Entries(ctx context.Context, term string) []util.Entry {
 	fields := strings.Fields(term)
 
-	cmd := "ssh"
+	if len(fields) < 2 {
+		return s.entries
+	}
 
-	for k, v := range s.entries {
-		if v.Sub == "SSH Host" {
-			if len(fields) > 1 {
-				cmd = fmt.Sprintf("ssh %s@%s", fields[1], v.Label)
-			}
+	entries := make([]util.Entry, len(s.hosts))
 
-			s.entries[k].Exec = cmd
-		}
+	for k, v := range s.hosts {
+		entries[k] = s.entry(v, fields[1])
 	}
 
-	return s.entries
+	return entries
 }
 
 func (s *SSH) Setup(cfg *config.Config) bool {
 	s.general = cfg.Builtins.SSH.GeneralModule
+	s.actions = []config.SSHAction{}
+	s.altAction = ""
+
+	for _, v := range cfg.Builtins.SSH.Actions {
+		if v.Cmd == "" {
+			builtin, ok := sshBuiltinActions[v.Name]
+			if !ok {
+				log.Printf("ssh: unknown action '%s'", v.Name)
+				continue
+			}
+
+			if path, _ := exec.LookPath(builtin.bin); path == "" {
+				continue
+			}
+		}
+
+		s.actions = append(s.actions, v)
+	}
+
+	if cfg.Builtins.SSH.AltAction != "" {
+		for _, v := range s.actions {
+			if v.Name != cfg.Builtins.SSH.AltAction {
+				continue
+			}
+
+			terminal := v.Terminal
+
+			if v.Cmd == "" {
+				terminal = sshBuiltinActions[v.Name].entry(sshHost{}, "").Terminal
+			}
+
+			if !terminal {
+				log.Printf("ssh: alt_action '%s' has to run in a terminal", v.Name)
+				break
+			}
+
+			s.altAction = v.Name
+		}
+	}
 
 	return true
 }
 
 func (s *SSH) SetupData(cfg *config.Config, ctx context.Context) {
+	s.hosts = loadSSHHosts(cfg.Builtins.SSH)
+	s.entries = []util.Entry{}
+
+	for _, v := range s.hosts {
+		s.entries = append(s.entries, s.entry(v, ""))
+	}
+
+	s.general.IsSetup = true
+	s.general.HasInitialSetup = true
+}
+
+// loadSSHHosts returns the hosts from the ssh config and known_hosts files.
+func loadSSHHosts(cfg config.SSH) []sshHost {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		log.Panicln(err)
-		return
+		return nil
 	}
 
-	sshCfg := filepath.Join(home, ".ssh", "config")
-	if cfg.Builtins.SSH.ConfigFile != "" {
-		sshCfg = cfg.Builtins.SSH.ConfigFile
+	sshDir := filepath.Join(home, ".ssh")
+
+	sshCfg := filepath.Join(sshDir, "config")
+	if cfg.ConfigFile != "" {
+		sshCfg = cfg.ConfigFile
 	}
 
-	hosts := filepath.Join(home, ".ssh", "known_hosts")
-	if cfg.Builtins.SSH.HostFile != "" {
-		hosts = cfg.Builtins.SSH.HostFile
+	hosts := filepath.Join(sshDir, "known_hosts")
+	if cfg.HostFile != "" {
+		hosts = cfg.HostFile
 	}
 
-	s.entries = append(s.entries, getHostFileEntries(hosts)...)
-	s.entries = append(s.entries, getConfigFileEntries(sshCfg)...)
+	return mergeSSHHosts(parseSSHConfig(sshCfg, sshDir), parseKnownHosts(hosts))
+}
 
-	s.general.IsSetup = true
-	s.general.HasInitialSetup = true
+func (s SSH) entry(h sshHost, user string) util.Entry {
+	categories := []string{"ssh"}
+
+	if h.HostName != "" {
+		categories = append(categories, h.HostName)
+	}
+
+	entry := util.Entry{
+		Label:            h.Alias,
+		Sub:              h.sub(),
+		Exec:             h.command(user),
+		MatchFields:      1,
+		Searchable:       h.Alias,
+		Terminal:         true,
+		TerminalTitle:    h.Alias,
+		Categories:       categories,
+		Class:            "ssh",
+		Matching:         util.Fuzzy,
+		RecalculateScore: true,
+	}
+
+	for _, v := range s.actions {
+		action := h.action(v, user)
+
+		if v.Name != "" && v.Name == s.altAction {
+			entry.ExecAlt = action.Exec
+		}
+
+		entry.Actions = append(entry.Actions, action)
+	}
+
+	return entry
 }
 
-func getConfigFileEntries(sshCfg string) []util.Entry {
-	entries := []util.Entry{}
+// action builds the entry for an action. Custom commands can use %HOST%, %HOSTNAME%, %USER% and %PORT%, which are
+// substituted shell-quoted.
+func (h sshHost) action(action config.SSHAction, user string) util.Entry {
+	var entry util.Entry
+
+	if action.Cmd == "" {
+		entry = sshBuiltinActions[action.Name].entry(h, user)
+	} else {
+		hostname := h.HostName
+		if hostname == "" {
+			hostname = h.Alias
+		}
 
-	file, err := os.Open(sshCfg)
-	if err != nil {
-		return []util.Entry{}
+		if user == "" {
+			user = h.User
+		}
+
+		port := h.Port
+		if port == "" {
+			port = "22"
+		}
+
+		entry = util.Entry{
+			Label: action.Name,
+			Exec: strings.NewReplacer(
+				"%HOST%", util.ShellQuote(h.Alias),
+				"%HOSTNAME%", util.ShellQuote(hostname),
+				"%USER%", util.ShellQuote(user),
+				"%PORT%", util.ShellQuote(port),
+			).Replace(action.Cmd),
+			Terminal: action.Terminal,
+		}
+	}
+
+	if action.Label != "" {
+		entry.Label = action.Label
 	}
 
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
+	entry.Sub = h.Alias
+	entry.Class = "ssh"
 
-	for scanner.Scan() {
-		text := scanner.Text()
-
-		if strings.HasPrefix(text, "Host ") || strings.HasPrefix(text, "host ") {
-			fields := strings.Fields(text)
-
-			entries = append(entries, util.Entry{
-				Label:            fields[1],
-				Sub:              "SSH Config",
-				Exec:             fmt.Sprintf("ssh %s", fields[1]),
-				Searchable:       fields[1],
-				Terminal:         true,
-				Categories:       []string{"ssh"},
-				Class:            "ssh",
-				Matching:         util.Fuzzy,
-				RecalculateScore: true,
-			})
+	if entry.Terminal {
+		entry.TerminalTitle = h.Alias
+	}
+
+	return entry
+}
+
+// sub renders the connection details as 'user@hostname:port'.
+func (h sshHost) sub() string {
+	if h.HostName == "" && h.User == "" && (h.Port == "" || h.Port == "22") {
+		if h.IsKnownHost {
+			return "SSH Host"
 		}
+
+		return "SSH Config"
 	}
 
-	return entries
+	target := h.HostName
+	if target == "" {
+		target = h.Alias
+	}
+
+	if h.User != "" {
+		target = fmt.Sprintf("%s@%s", h.User, target)
+	}
+
+	if h.Port != "" && h.Port != "22" {
+		target = fmt.Sprintf("%s:%s", target, h.Port)
+	}
+
+	return target
+}
+
+// target returns '[user@]alias' as passed to ssh.
+func (h sshHost) target(user string) string {
+	if user != "" {
+		return fmt.Sprintf("%s@%s", user, h.Alias)
+	}
+
+	return h.Alias
+}
+
+// explicitPort returns the port that has to be passed on the command line. Ports of ssh_config hosts are left to
+// ssh itself.
+func (h sshHost) explicitPort() string {
+	if h.IsKnownHost && h.Port != "" && h.Port != "22" {
+		return h.Port
+	}
+
+	return ""
+}
+
+func (h sshHost) command(user string) string {
+	if p := h.explicitPort(); p != "" {
+		return fmt.Sprintf("ssh -p %s %s", p, h.target(user))
+	}
+
+	return fmt.Sprintf("ssh %s", h.target(user))
+}
+
+// mergeSSHHosts lists every host once. Hosts from ssh_config win over known_hosts entries that refer to the same
+// alias or to the same HostName and Port.
+func mergeSSHHosts(configured []sshHost, known []sshHost) []sshHost {
+	res := slices.Clone(configured)
+	seen := make(map[string]struct{})
+
+	for _, v := range configured {
+		seen[strings.ToLower(v.Alias)] = struct{}{}
+
+		hostname := v.HostName
+		if hostname == "" {
+			hostname = v.Alias
+		}
+
+		seen[sshHostKey(hostname, v.Port)] = struct{}{}
+	}
+
+	for _, v := range known {
+		if _, ok := seen[strings.ToLower(v.Alias)]; ok && (v.Port == "" || v.Port == "22") {
+			continue
+		}
+
+		key := sshHostKey(v.Alias, v.Port)
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		res = append(res, v)
+	}
+
+	return res
+}
+
+func sshHostKey(host, port string) string {
+	if port == "" {
+		port = "22"
+	}
+
+	return fmt.Sprintf("%s:%s", strings.ToLower(host), port)
 }
 
-func getHostFileEntries(hosts string) []util.Entry {
-	file, err := os.Open(hosts)
+// parseKnownHosts reads host names and ports from a known_hosts file. Hashed hosts, negated or wildcard patterns and
+// lines with markers like '@cert-authority' or '@revoked' are skipped. IP addresses are only used if a line has no
+// host name.
+func parseKnownHosts(file string) []sshHost {
+	f, err := os.Open(file)
 	if err != nil {
-		return []util.Entry{}
+		return []sshHost{}
 	}
 
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
 
-	hs := make(map[string]struct{})
+	hosts := []sshHost{}
 
 	for scanner.Scan() {
-		host := strings.Fields(scanner.Text())[0]
-		hs[host] = struct{}{}
-	}
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
 
-	entries := []util.Entry{}
+		names := []sshHost{}
+		ips := []sshHost{}
+
+		for _, v := range strings.Split(fields[0], ",") {
+			if v == "" || strings.HasPrefix(v, "|") || strings.HasPrefix(v, "!") || strings.ContainsAny(v, "*?") {
+				continue
+			}
+
+			host := sshHost{
+				Alias:       v,
+				IsKnownHost: true,
+			}
 
-	for k := range hs {
-		entries = append(entries, util.Entry{
-			Label:            k,
-			Sub:              "SSH Host",
-			Exec:             "ssh",
-			MatchFields:      1,
-			Searchable:       k,
-			Terminal:         true,
-			Categories:       []string{"ssh"},
-			Class:            "ssh",
-			Matching:         util.Fuzzy,
-			RecalculateScore: true,
-		})
+			if strings.HasPrefix(v, "[") {
+				h, p, err := net.SplitHostPort(v)
+				if err != nil {
+					continue
+				}
+
+				host.Alias = h
+				host.Port = p
+			}
+
+			if net.ParseIP(host.Alias) != nil {
+				ips = append(ips, host)
+			} else {
+				names = append(names, host)
+			}
+		}
+
+		if len(names) > 0 {
+			hosts = append(hosts, names...)
+		} else {
+			hosts = append(hosts, ips...)
+		}
 	}
 
-	return entries
+	return hosts
 }
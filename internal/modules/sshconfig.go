@@ -0,0 +1,292 @@
+package modules
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSSHIncludeDepth mirrors the recursion limit of ssh(1).
+const maxSSHIncludeDepth = 16
+
+type sshHost struct {
+	Alias       string
+	HostName    string
+	User        string
+	Port        string
+	IsKnownHost bool
+}
+
+type sshBlock struct {
+	patterns []string
+	isMatch  bool
+	settings map[string]string
+}
+
+type sshConfigParser struct {
+	sshDir  string
+	blocks  []*sshBlock
+	visited map[string]struct{}
+}
+
+// parseSSHConfig reads an ssh_config file, following Include directives, and returns every concrete host alias
+// together with its effective HostName, User and Port.
+func parseSSHConfig(file string, sshDir string) []sshHost {
+	p := &sshConfigParser{
+		sshDir:  sshDir,
+		blocks:  []*sshBlock{{patterns: []string{"*"}, settings: make(map[string]string)}},
+		visited: make(map[string]struct{}),
+	}
+
+	p.parseFile(file, 0)
+
+	hosts := []sshHost{}
+	seen := make(map[string]struct{})
+
+	for _, b := range p.blocks {
+		if b.isMatch {
+			continue
+		}
+
+		for _, alias := range b.patterns {
+			if strings.HasPrefix(alias, "!") || strings.ContainsAny(alias, "*?") {
+				continue
+			}
+
+			if _, ok := seen[alias]; ok {
+				continue
+			}
+
+			seen[alias] = struct{}{}
+
+			hosts = append(hosts, p.resolve(alias))
+		}
+	}
+
+	return hosts
+}
+
+// resolve applies all matching Host blocks in order, the first obtained value wins.
+func (p *sshConfigParser) resolve(alias string) sshHost {
+	settings := make(map[string]string)
+
+	for _, b := range p.blocks {
+		if b.isMatch || !matchSSHPatterns(b.patterns, alias) {
+			continue
+		}
+
+		for k, v := range b.settings {
+			if _, ok := settings[k]; !ok {
+				settings[k] = v
+			}
+		}
+	}
+
+	host := sshHost{
+		Alias:    alias,
+		HostName: strings.ReplaceAll(settings["hostname"], "%h", alias),
+		User:     settings["user"],
+		Port:     settings["port"],
+	}
+
+	if host.HostName == alias {
+		host.HostName = ""
+	}
+
+	return host
+}
+
+func (p *sshConfigParser) parseFile(file string, depth int) {
+	if depth > maxSSHIncludeDepth {
+		return
+	}
+
+	if _, ok := p.visited[file]; ok {
+		return
+	}
+
+	p.visited[file] = struct{}{}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		keyword, args := splitSSHLine(scanner.Text())
+
+		if keyword == "" || len(args) == 0 {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			p.blocks = append(p.blocks, &sshBlock{patterns: args, settings: make(map[string]string)})
+		case "match":
+			p.blocks = append(p.blocks, &sshBlock{isMatch: true, settings: make(map[string]string)})
+		case "include":
+			for _, v := range args {
+				p.include(v, depth)
+			}
+		default:
+			current := p.blocks[len(p.blocks)-1]
+
+			if _, ok := current.settings[keyword]; !ok {
+				current.settings[keyword] = args[0]
+			}
+		}
+	}
+}
+
+func (p *sshConfigParser) include(pattern string, depth int) {
+	if strings.HasPrefix(pattern, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return
+		}
+
+		pattern = filepath.Join(home, strings.TrimPrefix(pattern, "~/"))
+	}
+
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(p.sshDir, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return
+	}
+
+	// settings of an included file only apply to the Host block it was included in, so restore it afterwards.
+	current := p.blocks[len(p.blocks)-1]
+
+	for _, v := range matches {
+		p.parseFile(v, depth+1)
+	}
+
+	if p.blocks[len(p.blocks)-1] != current {
+		p.blocks = append(p.blocks, &sshBlock{
+			patterns: current.patterns,
+			isMatch:  current.isMatch,
+			settings: current.settings,
+		})
+	}
+}
+
+// splitSSHLine returns the lowercased keyword and its arguments. Keywords and arguments may be separated by
+// whitespace or a single '=', arguments may be double-quoted.
+func splitSSHLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	i := strings.IndexAny(line, " \t=")
+	if i == -1 {
+		return strings.ToLower(line), nil
+	}
+
+	keyword := strings.ToLower(line[:i])
+	rest := strings.TrimSpace(line[i:])
+	rest = strings.TrimSpace(strings.TrimPrefix(rest, "="))
+
+	args := []string{}
+
+	var current strings.Builder
+	inQuotes := false
+	hasArg := false
+
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		case r == '#' && !inQuotes && !hasArg:
+			return keyword, args
+		default:
+			current.WriteRune(r)
+			hasArg = true
+		}
+	}
+
+	if hasArg {
+		args = append(args, current.String())
+	}
+
+	return keyword, args
+}
+
+// matchSSHPatterns reports whether host matches the pattern list. A matching negated pattern always rejects.
+func matchSSHPatterns(patterns []string, host string) bool {
+	matched := false
+
+	for _, v := range patterns {
+		if strings.HasPrefix(v, "!") {
+			if matchSSHPattern(strings.TrimPrefix(v, "!"), host) {
+				return false
+			}
+
+			continue
+		}
+
+		if matchSSHPattern(v, host) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchSSHPattern matches with the ssh_config wildcards '*' and '?'.
+func matchSSHPattern(pattern, s string) bool {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+
+	px, sx := 0, 0
+	nextPx, nextSx := -1, -1
+
+	for px < len(pattern) || sx < len(s) {
+		if px < len(pattern) {
+			switch c := pattern[px]; c {
+			case '*':
+				nextPx = px
+				nextSx = sx + 1
+				px++
+				continue
+			case '?':
+				if sx < len(s) {
+					px++
+					sx++
+					continue
+				}
+			default:
+				if sx < len(s) && s[sx] == c {
+					px++
+					sx++
+					continue
+				}
+			}
+		}
+
+		if nextSx > 0 && nextSx <= len(s) {
+			px = nextPx
+			sx = nextSx
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
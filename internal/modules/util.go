@@ -2,13 +2,7 @@ package modules
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
-	"os"
-	"path/filepath"
 
 	"github.com/abenz1267/walker/internal/config"
 	"github.com/abenz1267/walker/internal/util"
@@ -23,38 +17,43 @@ type Workable interface {
 	SetupData(cfg *config.Config, ctx context.Context)
 }
 
-func readCache(name string, data any) bool {
-	cacheDir, err := os.UserCacheDir()
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-
-	cacheDir = filepath.Join(cacheDir, "walker")
-
-	path := filepath.Join(cacheDir, fmt.Sprintf("%s.json", name))
+// Initial is implemented by modules providing the entries to show before anything is typed. They are ranked by
+// usage, modules without it show all of their entries.
+type Initial interface {
+	InitialEntries(ctx context.Context) []util.Entry
+}
 
-	if _, err := os.Stat(path); err == nil {
-		file, err := os.Open(path)
-		if err != nil {
-			log.Println(err)
-		}
-		defer file.Close()
+// OrderedInitial is implemented by modules whose initial entries are in a meaningful order already, f.e. windows by
+// their last activation. Their order is kept instead of ranking them by usage.
+type OrderedInitial interface {
+	Initial
+	KeepsInitialOrder()
+}
 
-		b, err := io.ReadAll(file)
-		if err != nil {
-			log.Panicln(err)
-		}
+// Completer is implemented by modules completing the input, f.e. arguments of commands. Completions are shown and
+// accepted like typeahead.
+type Completer interface {
+	Complete(ctx context.Context, term string) string
+}
 
-		err = json.Unmarshal(b, &data)
-		if err != nil {
-			log.Panicln(err)
-		}
+// Streamer is implemented by modules finding results over time. Batches are shown as they arrive, Stream returns
+// once everything is sent or ctx is cancelled.
+type Streamer interface {
+	Stream(ctx context.Context, term string, batches chan<- []util.Entry)
+}
 
-		return true
+// outputAction returns an action running the entry's command and showing its output as entries.
+func outputAction(e util.Entry) util.Entry {
+	return util.Entry{
+		Label:  "Show output",
+		Sub:    e.Label,
+		Exec:   e.Exec,
+		Env:    e.Env,
+		Path:   e.Path,
+		Piped:  e.Piped,
+		Icon:   "utilities-terminal",
+		Output: true,
 	}
-
-	return false
 }
 
 func Find(plugins []config.Plugin, name string) (config.Plugin, error) {
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,9 +27,12 @@ const (
 )
 
 type Websearch struct {
-	general    config.GeneralModule
-	engines    []string
-	engineInfo map[string]EngineInfo
+	general        config.GeneralModule
+	engines        []string
+	engineInfo     map[string]EngineInfo
+	engineSettings map[string]config.WebsearchEngine
+	opener         string
+	openerArgs     string
 }
 
 type EngineInfo struct {
@@ -42,6 +49,21 @@ func (w Websearch) Cleanup() {}
 func (w *Websearch) Setup(cfg *config.Config) bool {
 	w.engines = cfg.Builtins.Websearch.Engines
 	w.general = cfg.Builtins.Websearch.GeneralModule
+	w.opener = cfg.Builtins.Websearch.Opener
+	w.openerArgs = cfg.Builtins.Websearch.OpenerArgs
+	w.engineSettings = cfg.Builtins.Websearch.EngineSettings
+
+	if strings.TrimSpace(w.opener) == "" {
+		w.opener = "xdg-open"
+	}
+
+	fields := strings.Fields(w.opener)
+
+	path, _ := exec.LookPath(fields[0])
+	if path == "" {
+		log.Printf("%s not found. Disabling websearch.", fields[0])
+		return false
+	}
 
 	return true
 }
@@ -82,22 +104,18 @@ func (w *Websearch) Refresh() {
 func (w Websearch) Entries(ctx context.Context, term string) []util.Entry {
 	entries := []util.Entry{}
 
-	path, _ := exec.LookPath("xdg-open")
-	if path == "" {
-		log.Println("xdg-open not found. Disabling websearch.")
-		return nil
-	}
-
-	term = strings.TrimPrefix(term, w.general.Prefix)
+	term = strings.TrimSpace(strings.TrimPrefix(term, w.general.Prefix))
 
 	for k, v := range w.engines {
-		if val, ok := w.engineInfo[strings.ToLower(v)]; ok {
+		engine := strings.ToLower(v)
+
+		if val, ok := w.engineInfo[engine]; ok {
 			url := strings.ReplaceAll(val.URL, "%TERM%", url.QueryEscape(term))
 
 			n := util.Entry{
 				Label:      fmt.Sprintf("Search with %s", val.Label),
 				Sub:        "Websearch",
-				Exec:       fmt.Sprintf("xdg-open %s", url),
+				Exec:       w.openCmd(engine, url),
 				Class:      "websearch",
 				ScoreFinal: float64(k + 1),
 			}
@@ -106,22 +124,179 @@ func (w Websearch) Entries(ctx context.Context, term string) []util.Entry {
 		}
 	}
 
-	if strings.ContainsAny(term, ".") && !strings.HasSuffix(term, ".") {
-		_, err := url.ParseRequestURI(fmt.Sprintf("https://%s", term))
-		if err == nil {
-			entries = append(entries, util.Entry{
-				Label:    fmt.Sprintf("Visit https://%s", term),
-				Sub:      "Websearch",
-				Exec:     "xdg-open https://" + term,
-				Class:    "websearch",
-				Matching: util.AlwaysTop,
-			})
-		}
+	if target, ok := toURL(term); ok {
+		entries = append(entries, util.Entry{
+			Label:    fmt.Sprintf("Visit %s", target),
+			Sub:      "Websearch",
+			Exec:     w.openCmd("", target),
+			Class:    "websearch",
+			Matching: util.AlwaysTop,
+		})
 	}
 
 	return entries
 }
 
+// openCmd builds the command opening url, using the opener configured for the given engine or the global one.
+func (w Websearch) openCmd(engine, url string) string {
+	opener := w.opener
+	args := w.openerArgs
+
+	if val, ok := w.engineSettings[engine]; ok {
+		if val.Opener != "" {
+			opener = val.Opener
+		}
+
+		if val.OpenerArgs != "" {
+			args = val.OpenerArgs
+		}
+	}
+
+	quoted := util.ShellQuote(url)
+
+	if strings.Contains(opener, "%URL%") {
+		return strings.ReplaceAll(opener, "%URL%", strings.TrimSpace(fmt.Sprintf("%s %s", args, quoted)))
+	}
+
+	return strings.Join(strings.Fields(fmt.Sprintf("%s %s %s", opener, args, quoted)), " ")
+}
+
+var opaqueSchemes = []string{"mailto", "magnet", "tel"}
+
+// toURL checks if the term can be visited directly. Existing schemes are kept, scheme-less hosts get http or https.
+func toURL(term string) (string, bool) {
+	if term == "" || strings.ContainsAny(term, " \t\n") {
+		return "", false
+	}
+
+	if strings.HasPrefix(term, "/") || strings.HasPrefix(term, "~/") {
+		return fileURL(term)
+	}
+
+	if strings.Contains(term, "://") {
+		u, err := url.Parse(term)
+		if err != nil || u.Scheme == "" {
+			return "", false
+		}
+
+		if u.Scheme == "file" {
+			return fileURL(u.Path)
+		}
+
+		if u.Host == "" {
+			return "", false
+		}
+
+		return term, true
+	}
+
+	if scheme, rest, ok := strings.Cut(term, ":"); ok && slices.Contains(opaqueSchemes, strings.ToLower(scheme)) {
+		if rest == "" || (strings.ToLower(scheme) == "mailto" && !strings.Contains(rest, "@")) {
+			return "", false
+		}
+
+		return term, true
+	}
+
+	hostport := term
+
+	if i := strings.IndexAny(term, "/?#"); i != -1 {
+		hostport = term[:i]
+	}
+
+	host := hostport
+	port := ""
+
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+		port = p
+
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", false
+		}
+	}
+
+	if strings.Contains(host, ":") && !strings.HasPrefix(hostport, "[") {
+		return "", false
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	switch {
+	case strings.EqualFold(host, "localhost"), net.ParseIP(host) != nil:
+		return fmt.Sprintf("http://%s", term), true
+	case isDomain(host):
+		return fmt.Sprintf("https://%s", term), true
+	case port != "" && isHostLabel(host):
+		// single-label internal hosts, f.e. 'nas:5000'
+		return fmt.Sprintf("http://%s", term), true
+	}
+
+	return "", false
+}
+
+func fileURL(path string) (string, bool) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+
+		path = filepath.Join(home, strings.TrimPrefix(path, "~/"))
+	}
+
+	if !util.FileExists(path) {
+		return "", false
+	}
+
+	u := url.URL{Scheme: "file", Path: path}
+
+	return u.String(), true
+}
+
+func isDomain(host string) bool {
+	labels := strings.Split(host, ".")
+
+	if len(labels) < 2 {
+		return false
+	}
+
+	for _, v := range labels {
+		if !isHostLabel(v) {
+			return false
+		}
+	}
+
+	tld := labels[len(labels)-1]
+
+	return strings.ContainsFunc(tld, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '-'
+	})
+}
+
+func isHostLabel(label string) bool {
+	if label == "" || len(label) > 63 {
+		return false
+	}
+
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return false
+	}
+
+	for _, r := range label {
+		if r > 127 {
+			continue
+		}
+
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
 var httpClient = &http.Client{
 	Timeout: time.Second * 1,
 }
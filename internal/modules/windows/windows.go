@@ -2,7 +2,10 @@ package windows
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/abenz1267/walker/internal/config"
 	"github.com/abenz1267/walker/internal/modules/windows/wlr"
@@ -23,51 +26,157 @@ func (w Windows) General() *config.GeneralModule {
 func (w Windows) Cleanup() {
 }
 
+// Setup only checks if the compositor lists its windows, they are tracked once the module is used.
 func (w *Windows) Setup(cfg *config.Config) bool {
 	w.general = cfg.Builtins.Windows.GeneralModule
 
+	if err := wlr.Supported(); errors.Is(err, wlr.ErrUnsupported) {
+		log.Printf("Windows disabled: %s", err)
+		return false
+	}
+
 	return true
 }
 
+// SetupData starts tracking the windows, the module stays empty if the compositor doesn't list them.
 func (w *Windows) SetupData(cfg *config.Config, ctx context.Context) {
-	if !wlr.IsRunning {
-		go wlr.StartWM(nil, nil)
+	if err := wlr.StartWM(nil, nil); err != nil {
+		log.Printf("windows: %s", err)
 	}
 
 	w.general.IsSetup = true
 	w.general.HasInitialSetup = true
 }
 
+// Entries returns the windows, the most recently activated first.
 func (w Windows) Entries(ctx context.Context, term string) []util.Entry {
 	entries := []util.Entry{}
 
-	res := wlr.GetWindows()
+	showOutputs := wlr.OutputCount() > 1
+
+	for _, v := range wlr.GetWindowsByMRU() {
+		sub := fmt.Sprintf("Windows: %s", v.AppId)
+
+		if outputs := v.Outputs(); showOutputs && len(outputs) > 0 {
+			sub = fmt.Sprintf("%s on %s", sub, strings.Join(outputs, ", "))
+		}
+
+		classes := []string{}
+
+		if v.Minimized {
+			sub = fmt.Sprintf("%s (minimized)", sub)
+			classes = append(classes, "minimized")
+		}
+
+		if v.Activated {
+			classes = append(classes, "active")
+		}
 
-	for _, v := range res {
 		entries = append(entries, util.Entry{
 			Label:           v.Title,
-			Sub:             fmt.Sprintf("Windows: %s", v.AppId),
+			Sub:             sub,
 			Searchable:      v.AppId,
 			Categories:      []string{"windows"},
 			Class:           "windows",
+			Classes:         classes,
 			Matching:        util.Fuzzy,
 			SpecialFunc:     w.SpecialFunc,
 			SpecialFuncArgs: []interface{}{v.Toplevel.Id()},
+			Actions:         w.actions(v),
 		})
 	}
 
 	return entries
 }
 
+// InitialEntries returns all windows in the order they were activated, so the previous window comes second.
+func (w Windows) InitialEntries(ctx context.Context) []util.Entry {
+	return w.Entries(ctx, "")
+}
+
+// KeepsInitialOrder keeps the windows in the order they were activated, they would be sorted by title otherwise.
+func (w Windows) KeepsInitialOrder() {}
+
 func (w *Windows) Refresh() {
 }
 
+// actions returns the entries managing the window, they only differ from the window's entry by the action passed
+// to SpecialFunc.
+func (w Windows) actions(window wlr.Window) []util.Entry {
+	id := window.Toplevel.Id()
+
+	actions := []struct {
+		label  string
+		target interface{}
+		action wlr.Action
+	}{
+		{"Close", id, wlr.ActionClose},
+		{fmt.Sprintf("Close all %s windows", window.AppId), window.AppId, wlr.ActionClose},
+		{"Minimize", id, wlr.ActionMinimize},
+		{"Maximize", id, wlr.ActionMaximize},
+		{"Fullscreen", id, wlr.ActionFullscreen},
+	}
+
+	if window.Minimized {
+		actions[2].label, actions[2].action = "Unminimize", wlr.ActionUnminimize
+	}
+
+	if window.Maximized {
+		actions[3].label, actions[3].action = "Unmaximize", wlr.ActionUnmaximize
+	}
+
+	if window.Fullscreen {
+		actions[4].label, actions[4].action = "Leave fullscreen", wlr.ActionUnfullscreen
+	}
+
+	res := []util.Entry{}
+
+	for _, v := range actions {
+		res = append(res, util.Entry{
+			Label:           v.label,
+			Sub:             window.Title,
+			Class:           "windows",
+			SpecialFunc:     w.SpecialFunc,
+			SpecialFuncArgs: []interface{}{v.target, v.action},
+		})
+	}
+
+	return res
+}
+
+// SpecialFunc activates the window with the given id. If an action is given as well, it's performed instead. Given
+// an app_id instead of an id, the action is performed for all windows of the app.
 func (w Windows) SpecialFunc(args ...interface{}) {
 	if len(args) == 0 {
 		return
 	}
 
-	id := args[0].(wl.ProxyId)
+	if len(args) == 1 {
+		if err := wlr.Activate(args[0].(wl.ProxyId)); err != nil {
+			log.Printf("windows: %s", err)
+		}
 
-	wlr.Activate(id)
+		return
+	}
+
+	action := args[1].(wlr.Action)
+
+	ids := []wl.ProxyId{}
+
+	switch target := args[0].(type) {
+	case wl.ProxyId:
+		ids = append(ids, target)
+	case string:
+		for k, v := range wlr.GetWindows() {
+			if v.AppId == target {
+				ids = append(ids, k)
+			}
+		}
+	}
+
+	for _, v := range ids {
+		if err := wlr.Perform(v, action); err != nil {
+			log.Printf("windows: %s", err)
+		}
+	}
 }
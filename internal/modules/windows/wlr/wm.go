@@ -1,69 +1,453 @@
 package wlr
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/neurlang/wayland/wl"
-	"github.com/neurlang/wayland/wlclient"
 )
 
+// ErrUnsupported is returned if the compositor doesn't list its windows, f.e. GNOME and KDE.
+var ErrUnsupported = errors.New("compositor doesn't support zwlr_foreign_toplevel_manager_v1")
+
+var errWindowNotFound = errors.New("window not found")
+
+// mu guards the connection and the state of the compositor. Handlers run on the dispatching goroutine, the exported
+// functions are called from others. Channels are never sent to while holding it.
+var mu sync.Mutex
+
 var (
-	registry *wl.Registry
-	display  *wl.Display
-	seat     []*wl.Seat
+	registry   *wl.Registry
+	display    *wl.Display
+	seat       []*wl.Seat
+	hasManager bool
 )
 
-type windowmap map[wl.ProxyId]*Window
+var windows = make(map[wl.ProxyId]*Window)
 
-var windows = make(windowmap)
+// outputs maps the bound outputs to their names.
+var outputs = make(map[wl.ProxyId]string)
 
-var IsRunning = false
+// activations counts how often windows got activated, used to order them by most recent activation.
+var activations uint64
 
-func GetWindows() windowmap {
-	return windows
+var (
+	addChan    chan string
+	deleteChan chan string
+)
+
+var (
+	startMu     sync.Mutex
+	started     bool
+	unsupported bool
+	running     atomic.Bool
+)
+
+// IsRunning reports if the windows are currently known, it's false while reconnecting.
+func IsRunning() bool {
+	return running.Load()
 }
 
-func Activate(id wl.ProxyId) {
-	err := windows[id].Toplevel.Activate(seat[len(seat)-1])
+// GetWindows returns a copy of the current windows.
+func GetWindows() map[wl.ProxyId]Window {
+	mu.Lock()
+	defer mu.Unlock()
+
+	res := make(map[wl.ProxyId]Window, len(windows))
+
+	for k, v := range windows {
+		res[k] = v.copy()
+	}
+
+	return res
+}
+
+// GetWindowsByMRU returns a copy of the windows, the most recently activated first. Windows that haven't been
+// activated since starting keep the compositor's order at the end.
+func GetWindowsByMRU() []Window {
+	mu.Lock()
+	res := []Window{}
+
+	for _, v := range windows {
+		res = append(res, v.copy())
+	}
+	mu.Unlock()
+
+	slices.SortFunc(res, func(a, b Window) int {
+		if a.LastActivated != b.LastActivated {
+			if a.LastActivated > b.LastActivated {
+				return -1
+			}
+
+			return 1
+		}
+
+		return int(a.Toplevel.Id()) - int(b.Toplevel.Id())
+	})
+
+	return res
+}
+
+// OutputCount returns the number of outputs.
+func OutputCount() int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return len(outputs)
+}
+
+func Activate(id wl.ProxyId) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	window, ok := windows[id]
+	if !ok {
+		return errWindowNotFound
+	}
+
+	if len(seat) == 0 {
+		return errors.New("no seat to activate the window with")
+	}
+
+	err := window.Toplevel.Activate(seat[len(seat)-1])
 	if err != nil {
-		log.Fatalf("unable to activate toplevel: %v", err)
+		return fmt.Errorf("unable to activate toplevel: %w", err)
 	}
+
+	return nil
 }
 
-var (
-	addChan    chan string
-	deleteChan chan string
+// Action is a request for a window, besides activating it.
+type Action int
+
+const (
+	ActionClose Action = iota
+	ActionMinimize
+	ActionUnminimize
+	ActionMaximize
+	ActionUnmaximize
+	ActionFullscreen
+	ActionUnfullscreen
 )
 
-func StartWM(ac chan string, dc chan string) {
+// Perform sends the request for the action to the compositor. Fullscreen windows stay on the output chosen by the
+// compositor.
+func Perform(id wl.ProxyId, action Action) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	window, ok := windows[id]
+	if !ok {
+		return errWindowNotFound
+	}
+
+	switch action {
+	case ActionClose:
+		return window.Toplevel.Close()
+	case ActionMinimize:
+		return window.Toplevel.SetMinimized()
+	case ActionUnminimize:
+		return window.Toplevel.UnsetMinimized()
+	case ActionMaximize:
+		return window.Toplevel.SetMaximized()
+	case ActionUnmaximize:
+		return window.Toplevel.UnsetMaximized()
+	case ActionFullscreen:
+		return window.Toplevel.SetFullscreen(nil)
+	case ActionUnfullscreen:
+		return window.Toplevel.UnsetFullscreen()
+	}
+
+	return errors.New("unknown action")
+}
+
+// FindByAppId returns a window with one of the given app_ids, compared case-insensitively.
+func FindByAppId(ids ...string) (wl.ProxyId, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for k, v := range windows {
+		for _, id := range ids {
+			if id != "" && strings.EqualFold(v.AppId, id) {
+				return k, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// StartWM connects to the compositor and keeps track of its windows in the background, reconnecting if the
+// connection is lost or can't be established yet. It returns once the initial windows are known or connecting failed.
+// Only compositors not listing their windows are an error, ErrUnsupported. The channels receive the app_id of every
+// added and closed window, calling it again only registers them and announces the current windows.
+func StartWM(ac chan string, dc chan string) error {
+	startMu.Lock()
+	defer startMu.Unlock()
+
+	if unsupported {
+		return ErrUnsupported
+	}
+
+	if started {
+		registerChannels(ac, dc)
+		return nil
+	}
+
+	mu.Lock()
 	addChan = ac
 	deleteChan = dc
+	mu.Unlock()
 
-	var err error
+	err := connect()
+	if errors.Is(err, ErrUnsupported) {
+		unsupported = true
+		return err
+	}
 
-	display, err = wl.Connect("")
 	if err != nil {
-		log.Fatalf("unable to connect to wayland server: %v", err)
+		log.Printf("%v, retrying in the background", err)
 	}
 
-	display.AddErrorHandler(displayErrorHandler{})
+	started = true
+
+	go run(err == nil)
+
+	return nil
+}
+
+// Supported reports ErrUnsupported if the compositor doesn't list its windows. Only the globals are queried, nothing
+// is tracked. Other errors, f.e. if the compositor can't be reached right now, are returned as well.
+func Supported() error {
+	startMu.Lock()
+	defer startMu.Unlock()
 
-	registry, err = display.GetRegistry()
+	if unsupported {
+		return ErrUnsupported
+	}
+
+	if started {
+		return nil
+	}
+
+	d, err := wl.Connect("")
+	if err != nil {
+		return fmt.Errorf("unable to connect to wayland server: %w", err)
+	}
+	defer d.Context().Close()
+
+	r, err := d.GetRegistry()
 	if err != nil {
-		log.Fatalf("unable to get global registry object: %v", err)
+		return fmt.Errorf("unable to get global registry object: %w", err)
 	}
 
-	registry.AddGlobalHandler(registryGlobalHander{})
+	globals := &globalsHandler{}
+	r.AddGlobalHandler(globals)
 
-	_ = wlclient.DisplayRoundtrip(display)
+	if err := roundtrip(d); err != nil {
+		return fmt.Errorf("unable to receive globals: %w", err)
+	}
 
-	IsRunning = true
+	if !slices.Contains(globals.interfaces, "zwlr_foreign_toplevel_manager_v1") {
+		unsupported = true
+		return ErrUnsupported
+	}
 
-	for {
-		err = display.Context().Run()
+	return nil
+}
+
+// globalsHandler collects the interfaces of the globals without binding them.
+type globalsHandler struct {
+	interfaces []string
+}
+
+func (h *globalsHandler) HandleRegistryGlobal(e wl.RegistryGlobalEvent) {
+	h.interfaces = append(h.interfaces, e.Interface)
+}
+
+func registerChannels(ac chan string, dc chan string) {
+	mu.Lock()
+
+	if dc != nil {
+		deleteChan = dc
+	}
+
+	if ac == nil {
+		mu.Unlock()
+		return
+	}
+
+	addChan = ac
+
+	ids := []string{}
+
+	for _, v := range windows {
+		if v.AppId != "" {
+			ids = append(ids, v.AppId)
+		}
+	}
+	mu.Unlock()
+
+	for _, v := range ids {
+		ac <- v
+	}
+}
+
+// connect binds the globals and receives the initial windows.
+func connect() error {
+	d, err := wl.Connect("")
+	if err != nil {
+		return fmt.Errorf("unable to connect to wayland server: %w", err)
+	}
+
+	d.AddErrorHandler(displayErrorHandler{})
+
+	r, err := d.GetRegistry()
+	if err != nil {
+		d.Context().Close()
+		return fmt.Errorf("unable to get global registry object: %w", err)
+	}
+
+	mu.Lock()
+	display = d
+	registry = r
+	hasManager = false
+	// failed attempts may have bound some globals already
+	outputs = make(map[wl.ProxyId]string)
+	seat = nil
+	mu.Unlock()
+
+	r.AddGlobalHandler(registryGlobalHander{})
+
+	// the first roundtrip binds the globals, the second one receives the windows and their details
+	for i := 0; i < 2; i++ {
+		err = roundtrip(d)
 		if err != nil {
-			log.Fatalf("error when running: %v", err)
+			d.Context().Close()
+			return fmt.Errorf("unable to receive globals: %w", err)
+		}
+	}
+
+	mu.Lock()
+	supported := hasManager
+	mu.Unlock()
+
+	if !supported {
+		d.Context().Close()
+		return ErrUnsupported
+	}
+
+	running.Store(true)
+
+	return nil
+}
+
+func roundtrip(d *wl.Display) error {
+	cb, err := d.Sync()
+	if err != nil {
+		return err
+	}
+
+	for {
+		err = d.Context().RunTill(cb)
+		if err == nil || !isTransient(err) {
+			return err
+		}
+	}
+}
+
+// run dispatches events. If the connection is lost, the windows are dropped and it reconnects. It stops if the
+// compositor turns out not to list its windows.
+func run(connected bool) {
+	for {
+		if !connected && !reconnect() {
+			return
+		}
+
+		mu.Lock()
+		ctx := display.Context()
+		mu.Unlock()
+
+		err := ctx.Run()
+		if err == nil || isTransient(err) {
+			continue
+		}
+
+		log.Printf("wayland connection lost: %v", err)
+
+		disconnect()
+
+		connected = false
+	}
+}
+
+// reconnect connects with increasing delays until it succeeds. It gives up on ErrUnsupported, as retrying won't
+// help.
+func reconnect() bool {
+	for delay := time.Second; ; delay = min(delay*2, 30*time.Second) {
+		time.Sleep(delay)
+
+		err := connect()
+		if err == nil {
+			return true
+		}
+
+		if errors.Is(err, ErrUnsupported) {
+			log.Printf("%v, stopped tracking windows", err)
+
+			startMu.Lock()
+			unsupported = true
+			startMu.Unlock()
+
+			return false
+		}
+
+		log.Printf("unable to reconnect: %v", err)
+	}
+}
+
+// isTransient reports if dispatching can go on after the error, f.e. for events of unknown objects.
+func isTransient(err error) bool {
+	if errors.Is(err, wl.ErrContextRunTimeout) || errors.Is(err, wl.ErrContextRunProxyNil) || errors.Is(err, wl.ErrContextRunNotDispatched) {
+		return true
+	}
+
+	var combined interface{ External() error }
+
+	return errors.As(err, &combined) && combined.External() == wl.ErrContextRunProtocolError
+}
+
+// disconnect closes the connection and drops all state, the windows are announced as closed.
+func disconnect() {
+	running.Store(false)
+
+	mu.Lock()
+	display.Context().Close()
+
+	closed := []string{}
+
+	for _, v := range windows {
+		if v.AppId != "" {
+			closed = append(closed, v.AppId)
+		}
+	}
+
+	windows = make(map[wl.ProxyId]*Window)
+	outputs = make(map[wl.ProxyId]string)
+	seat = nil
+
+	dc := deleteChan
+	mu.Unlock()
+
+	if dc != nil {
+		for _, v := range closed {
+			dc <- v
 		}
 	}
 }
@@ -71,78 +455,195 @@ func StartWM(ac chan string, dc chan string) {
 type displayErrorHandler struct{}
 
 func (displayErrorHandler) HandleDisplayError(e wl.DisplayErrorEvent) {
-	log.Fatalf("display error event: %v", e)
+	// the compositor closes the connection afterwards, so it's reconnected
+	log.Printf("display error event: %v", e)
 }
 
 type registryGlobalHander struct{}
 
 func (registryGlobalHander) HandleRegistryGlobal(e wl.RegistryGlobalEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+
 	switch e.Interface {
 	case "zwlr_foreign_toplevel_manager_v1":
 		manager := NewZwlrForeignToplevelManagerV1(display.Context())
 
 		err := registry.Bind(e.Name, e.Interface, e.Version, manager)
 		if err != nil {
-			log.Fatalf("unable to bind wl_compositor interface: %v", err)
+			log.Printf("unable to bind zwlr_foreign_toplevel_manager_v1 interface: %v", err)
+			return
 		}
 
-		manager.AddToplevelHandler(&Window{})
+		manager.AddToplevelHandler(managerHandler{})
+		hasManager = true
+	case "wl_output":
+		output := wl.NewOutput(display.Context())
+
+		// names were added in version 4
+		err := registry.Bind(e.Name, e.Interface, min(e.Version, 4), output)
+		if err != nil {
+			log.Printf("unable to bind wl_output interface: %v", err)
+			return
+		}
+
+		outputs[output.Id()] = ""
+		output.AddNameHandler(outputNameHandler{id: output.Id()})
 	case "wl_seat":
-		seat = append(seat, wl.NewSeat(display.Context()))
+		s := wl.NewSeat(display.Context())
 
-		err := registry.Bind(e.Name, e.Interface, e.Version, seat[len(seat)-1])
+		err := registry.Bind(e.Name, e.Interface, e.Version, s)
 		if err != nil {
-			log.Fatalf("unable to bind wl_seat interface: %v", err)
+			log.Printf("unable to bind wl_seat interface: %v", err)
+			return
 		}
+
+		seat = append(seat, s)
 	}
 }
 
+type outputNameHandler struct {
+	id wl.ProxyId
+}
+
+func (h outputNameHandler) HandleOutputName(e wl.OutputNameEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	outputs[h.id] = e.Name
+}
+
+type managerHandler struct{}
+
+func (managerHandler) HandleZwlrForeignToplevelManagerV1Toplevel(e ZwlrForeignToplevelManagerV1ToplevelEvent) {
+	window := &Window{
+		Toplevel: e.Toplevel,
+	}
+
+	e.Toplevel.AddTitleHandler(window)
+	e.Toplevel.AddAppIdHandler(window)
+	e.Toplevel.AddClosedHandler(window)
+	e.Toplevel.AddStateHandler(window)
+	e.Toplevel.AddOutputEnterHandler(window)
+	e.Toplevel.AddOutputLeaveHandler(window)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	windows[e.Toplevel.Id()] = window
+}
+
+// Window is a toplevel of the compositor. The exported functions return copies, the handlers update the original.
 type Window struct {
-	mutex      sync.Mutex
-	Toplevel   *ZwlrForeignToplevelHandleV1
-	AppId      string
-	Title      string
-	AddChan    chan string
-	DeleteChan chan string
+	Toplevel      *ZwlrForeignToplevelHandleV1
+	AppId         string
+	Title         string
+	Activated     bool
+	Minimized     bool
+	Maximized     bool
+	Fullscreen    bool
+	LastActivated uint64
+	outputIds     []wl.ProxyId
+}
+
+func (w *Window) copy() Window {
+	res := *w
+	res.outputIds = slices.Clone(w.outputIds)
+
+	return res
 }
 
-func (*Window) HandleZwlrForeignToplevelManagerV1Toplevel(e ZwlrForeignToplevelManagerV1ToplevelEvent) {
-	handler := &Window{
-		Toplevel:   e.Toplevel,
-		AddChan:    addChan,
-		DeleteChan: deleteChan,
+// Outputs returns the names of the outputs the window is on.
+func (w Window) Outputs() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	res := []string{}
+
+	for _, v := range w.outputIds {
+		if name := outputs[v]; name != "" {
+			res = append(res, name)
+		}
 	}
 
-	e.Toplevel.AddTitleHandler(handler)
-	e.Toplevel.AddAppIdHandler(handler)
-	e.Toplevel.AddClosedHandler(handler)
+	return res
+}
+
+func (w *Window) HandleZwlrForeignToplevelHandleV1Closed(e ZwlrForeignToplevelHandleV1ClosedEvent) {
+	mu.Lock()
+	delete(windows, w.Toplevel.Id())
+	appId, dc := w.AppId, deleteChan
+	mu.Unlock()
 
-	windows[e.Toplevel.Id()] = &Window{Toplevel: e.Toplevel}
+	if dc != nil {
+		dc <- appId
+	}
 }
 
-func (h *Window) HandleZwlrForeignToplevelHandleV1Closed(e ZwlrForeignToplevelHandleV1ClosedEvent) {
-	if h.DeleteChan != nil {
-		h.DeleteChan <- h.AppId
+func (w *Window) HandleZwlrForeignToplevelHandleV1AppId(e ZwlrForeignToplevelHandleV1AppIdEvent) {
+	mu.Lock()
+	previous := w.AppId
+	w.AppId = e.AppId
+	ac, dc := addChan, deleteChan
+	mu.Unlock()
+
+	if previous == e.AppId {
+		return
+	}
+
+	if dc != nil && previous != "" {
+		dc <- previous
 	}
 
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	delete(windows, h.Toplevel.Id())
+	if ac != nil {
+		ac <- e.AppId
+	}
+}
+
+func (w *Window) HandleZwlrForeignToplevelHandleV1Title(e ZwlrForeignToplevelHandleV1TitleEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	w.Title = e.Title
+}
+
+func (w *Window) HandleZwlrForeignToplevelHandleV1State(e ZwlrForeignToplevelHandleV1StateEvent) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	wasActivated := w.Activated
+
+	w.Activated = slices.Contains(e.State, ZwlrForeignToplevelHandleV1StateActivated)
+	w.Minimized = slices.Contains(e.State, ZwlrForeignToplevelHandleV1StateMinimized)
+	w.Maximized = slices.Contains(e.State, ZwlrForeignToplevelHandleV1StateMaximized)
+	w.Fullscreen = slices.Contains(e.State, ZwlrForeignToplevelHandleV1StateFullscreen)
+
+	if w.Activated && !wasActivated {
+		activations++
+		w.LastActivated = activations
+	}
 }
 
-func (h *Window) HandleZwlrForeignToplevelHandleV1AppId(e ZwlrForeignToplevelHandleV1AppIdEvent) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	windows[h.Toplevel.Id()].AppId = e.AppId
-	h.AppId = e.AppId
+func (w *Window) HandleZwlrForeignToplevelHandleV1OutputEnter(e ZwlrForeignToplevelHandleV1OutputEnterEvent) {
+	if e.Output == nil {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
 
-	if h.AddChan != nil {
-		h.AddChan <- e.AppId
+	if !slices.Contains(w.outputIds, e.Output.Id()) {
+		w.outputIds = append(w.outputIds, e.Output.Id())
 	}
 }
 
-func (h *Window) HandleZwlrForeignToplevelHandleV1Title(e ZwlrForeignToplevelHandleV1TitleEvent) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	windows[h.Toplevel.Id()].Title = e.Title
+func (w *Window) HandleZwlrForeignToplevelHandleV1OutputLeave(e ZwlrForeignToplevelHandleV1OutputLeaveEvent) {
+	if e.Output == nil {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	w.outputIds = slices.DeleteFunc(w.outputIds, func(id wl.ProxyId) bool { return id == e.Output.Id() })
 }
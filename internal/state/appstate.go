@@ -12,7 +12,9 @@ import (
 
 type AppState struct {
 	ActiveItem          *int
+	AltTab              bool
 	Clipboard           modules.Workable
+	Cwd                 string
 	IsDmenu             bool
 	Dmenu               *modules.Dmenu
 	DmenuSeparator      string
@@ -20,12 +22,15 @@ type AppState struct {
 	ExplicitConfig      string
 	ExplicitModules     []string
 	ExplicitPlaceholder string
+	ExplicitRoots       []string
+	ExplicitDirsOnly    bool
 	ExplicitTheme       string
 	ForcePrint          bool
 	HasUI               bool
 	IsRunning           bool
 	IsService           bool
 	KeepSort            bool
+	OpenWith            string
 	Password            bool
 	Benchmark           bool
 	IsSingle            bool
@@ -90,7 +90,9 @@ func sortEntries(entries []util.Entry) {
 			max := a.ScoreFinal + 50
 
 			if min < b.ScoreFinal && b.ScoreFinal < max {
-				if a.Module != b.Module {
+				// entries of the same module only have different weights if they set their own, f.e. via
+				// application overrides
+				if a.Module != b.Module || a.Weight != b.Weight {
 					if a.Weight > b.Weight {
 						return -1
 					}
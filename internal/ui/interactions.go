@@ -187,16 +187,28 @@ func disableAM() {
 }
 
 func handleGlobalKeysReleased(val, code uint, state gdk.ModifierType) {
+	if appstate.AltTab && slices.Contains(altTabModifiers, val) {
+		activateItem(false, false, false)
+		return
+	}
+
 	switch val {
 	case amKey:
 		disableAM()
 	}
 }
 
+// altTabModifiers are the keys that, when released, activate the selected window in alt-tab mode.
+var altTabModifiers = []uint{
+	gdk.KEY_Alt_L, gdk.KEY_Alt_R, gdk.KEY_Super_L, gdk.KEY_Super_R, gdk.KEY_Control_L, gdk.KEY_Control_R,
+	gdk.KEY_Meta_L, gdk.KEY_Meta_R,
+}
+
 func handleGlobalKeysPressed(val uint, code uint, modifier gdk.ModifierType) bool {
 	switch val {
 	case amKey:
-		if !cfg.ActivationMode.Disabled && common.selection.NItems() != 0 {
+		// in alt-tab mode the key is a modifier of the switcher, releasing it activates the selected window
+		if !cfg.ActivationMode.Disabled && !appstate.AltTab && common.selection.NItems() != 0 {
 			if val == amKey {
 				enableAM()
 				return true
@@ -229,6 +241,10 @@ func handleGlobalKeysPressed(val uint, code uint, modifier gdk.ModifierType) boo
 			return true
 		}
 	case gdk.KEY_Return:
+		if modifier == amModifier && showActions() {
+			return true
+		}
+
 		isShift := modifier == gdk.ShiftMask
 		isAlt := modifier == cmdAltModifier
 
@@ -364,7 +380,13 @@ func activateItem(keepOpen, selectNext, alt bool) {
 
 	entry := gioutil.ObjectValue[util.Entry](common.items.Item(common.selection.Selected()))
 
-	if !keepOpen && entry.Sub != "switcher" && cfg.IsService {
+	// entries showing output or feeding the input keep the window open
+	stays := entry.Output || entry.Class == outputFeedClass
+
+	// quitting resets the state
+	isDmenu := appstate.IsDmenu
+
+	if !keepOpen && entry.Sub != "switcher" && cfg.IsService && !stays {
 		go quit()
 	}
 
@@ -374,6 +396,20 @@ func activateItem(keepOpen, selectNext, alt bool) {
 		return
 	}
 
+	if entry.Class == modules.ApplicationsName && cfg.Builtins.Applications.FocusRunning {
+		if !alt && modules.FocusRunning(entry) {
+			if entry.History {
+				hstry.Save(entry.Identifier(), strings.TrimSpace(elements.input.Text()))
+			}
+
+			closeAfterActivation(keepOpen, selectNext)
+			return
+		}
+
+		// Alt+Enter launches a new instance
+		alt = false
+	}
+
 	toRun := entry.Exec
 
 	forceTerminal := false
@@ -386,10 +422,15 @@ func activateItem(keepOpen, selectNext, alt bool) {
 		}
 	}
 
-	if appstate.IsDmenu {
-		handleDmenuResult(toRun)
-		closeAfterActivation(keepOpen, selectNext)
-		return
+	if isDmenu {
+		// actions of dmenu entries, f.e. opening a file with an application, are run and reply nothing
+		if entry.Module == modules.MenuName {
+			handleDmenuResult("")
+		} else {
+			handleDmenuResult(toRun)
+			closeAfterActivation(keepOpen, selectNext)
+			return
+		}
 	}
 
 	if entry.Sub == "Walker" {
@@ -422,16 +463,26 @@ func activateItem(keepOpen, selectNext, alt bool) {
 		}
 	}
 
+	if entry.Class == outputFeedClass {
+		feedInput(entry.Exec)
+		return
+	}
+
+	if entry.Output {
+		showOutput(entry, toRun)
+		return
+	}
+
 	if cfg.Terminal != "" {
 		if entry.Terminal || forceTerminal {
-			toRun = fmt.Sprintf("%s -e %s", cfg.Terminal, toRun)
+			toRun = fmt.Sprintf("%s%s -e %s", cfg.Terminal, terminalTitle(entry.TerminalTitle), toRun)
 		}
 	} else {
 		log.Println("terminal is not set")
 		return
 	}
 
-	cmd := exec.Command("sh", "-c", toRun)
+	cmd := launchCommand(entry, toRun)
 
 	if entry.Path != "" {
 		cmd.Dir = entry.Path
@@ -469,6 +520,83 @@ func activateItem(keepOpen, selectNext, alt bool) {
 	closeAfterActivation(keepOpen, selectNext)
 }
 
+var terminalTitleFlags = map[string]string{
+	"alacritty":      "--title",
+	"foot":           "--title",
+	"ghostty":        "--title=",
+	"gnome-terminal": "--title",
+	"kitty":          "--title",
+	"lxterminal":     "--title",
+	"mate-terminal":  "--title",
+	"rxvt":           "-title",
+	"st":             "-t",
+	"terminator":     "--title",
+	"tilix":          "--title",
+	"urxvt":          "-title",
+	"uxterm":         "-T",
+	"xfce4-terminal": "--title",
+	"xterm":          "-T",
+}
+
+// terminalTitle returns the arguments setting the window title of the configured terminal, if it's known.
+func terminalTitle(title string) string {
+	if title == "" {
+		return ""
+	}
+
+	flag := cfg.TerminalTitleFlag
+
+	if flag == "" {
+		flag = terminalTitleFlags[filepath.Base(cfg.Terminal)]
+	}
+
+	if flag == "" {
+		return ""
+	}
+
+	if strings.HasSuffix(flag, "=") {
+		return fmt.Sprintf(" %s%s", flag, util.ShellQuote(title))
+	}
+
+	return fmt.Sprintf(" %s %s", flag, util.ShellQuote(title))
+}
+
+// showActions replaces the list with the actions of the selected entry.
+func showActions() bool {
+	if common.selection.NItems() == 0 {
+		return false
+	}
+
+	entry := gioutil.ObjectValue[util.Entry](common.items.Item(common.selection.Selected()))
+
+	actions := entry.Actions
+
+	if entry.ActionsFunc != nil {
+		actions = entry.ActionsFunc()
+	}
+
+	if len(actions) == 0 {
+		return false
+	}
+
+	showMenu(entry.Label, actions)
+
+	return true
+}
+
+func showMenu(placeholder string, entries []util.Entry) {
+	explicits = []modules.Workable{modules.NewMenu(placeholder, entries)}
+
+	common.items.Splice(0, int(common.items.NItems()))
+	elements.input.SetObjectProperty("placeholder-text", placeholder)
+	setupSingleModule()
+
+	elements.input.SetText("")
+	elements.input.GrabFocus()
+
+	process()
+}
+
 func handleDmenuResult(result string) {
 	if appstate.IsService {
 		for _, v := range toUse {
@@ -534,15 +662,19 @@ func process() {
 
 	text := strings.TrimSpace(elements.input.Text())
 
-	if text == "" && cfg.List.ShowInitialEntries && len(explicits) == 0 && !appstate.IsDmenu {
-		setInitials()
-		return
+	if text == "" && cfg.List.ShowInitialEntries && !appstate.IsDmenu {
+		if initials := initialModules(); len(initials) > 0 {
+			setInitials(initials)
+			return
+		}
 	}
 
 	var ctx context.Context
 	ctx, cancel = context.WithCancel(context.Background())
 
-	if (elements.input.Text() != "" || appstate.IsDmenu) || (len(explicits) > 0 && cfg.List.ShowInitialEntries) {
+	isMenu := len(explicits) == 1 && explicits[0].General().Name == modules.MenuName
+
+	if (elements.input.Text() != "" || appstate.IsDmenu || isMenu) || (len(explicits) > 0 && cfg.List.ShowInitialEntries) {
 		if !layout.Window.Box.Search.Spinner.Hide {
 			elements.spinner.SetVisible(true)
 		}
@@ -615,7 +747,7 @@ func processAsync(ctx context.Context, text string) {
 		p = explicits
 	}
 
-	setTypeahead(p)
+	setTypeahead(ctx, p)
 
 	handler.receiver = make(chan []util.Entry)
 	go handler.handle()
@@ -675,63 +807,12 @@ func processAsync(ctx context.Context, text string) {
 				return
 			}
 
-			e := w.Entries(ctx, text)
-
-			toPush := []util.Entry{}
-			g := w.General()
-
-			for k := range e {
-				e[k].Module = g.Name
-				e[k].Weight = g.Weight
-
-				if e[k].DragDrop && !elements.grid.CanTarget() {
-					elements.grid.SetCanTarget(true)
-				}
-
-				toMatch := text
-
-				if e[k].MatchFields > 0 {
-					textFields := strings.Fields(text)
-
-					if len(textFields) > 0 {
-						toMatch = strings.Join(textFields[:1], " ")
-					}
-				}
-
-				if e[k].RecalculateScore {
-					e[k].ScoreFinal = 0
-					e[k].ScoreFuzzy = 0
-				}
-
-				if e[k].ScoreFinal == 0 {
-					switch e[k].Matching {
-					case util.AlwaysTopOnEmptySearch:
-						if text != "" {
-							e[k].ScoreFinal = fuzzyScore(e[k], toMatch)
-						} else {
-							e[k].ScoreFinal = 1000
-						}
-					case util.Fuzzy:
-						e[k].ScoreFinal = fuzzyScore(e[k], toMatch)
-					case util.AlwaysTop:
-						if e[k].ScoreFinal == 0 {
-							e[k].ScoreFinal = 1000
-						}
-					case util.AlwaysBottom:
-						if e[k].ScoreFinal == 0 {
-							e[k].ScoreFinal = 1
-						}
-					default:
-						e[k].ScoreFinal = 0
-					}
-				}
-
-				if e[k].ScoreFinal != 0 {
-					toPush = append(toPush, e[k])
-				}
+			if st, ok := w.(modules.Streamer); ok {
+				streamEntries(ctx, st, w.General(), text, handler)
+				return
 			}
 
-			handler.receiver <- toPush
+			handler.receiver <- scoreEntries(w.Entries(ctx, text), w.General(), text)
 		}(ctx, &wg, text, p[k])
 	}
 
@@ -760,19 +841,113 @@ func processAsync(ctx context.Context, text string) {
 	}
 }
 
-func setTypeahead(modules []modules.Workable) {
-	if elements.input.Text() == "" {
+// scoreEntries sets the module and score of the entries and returns the matching ones.
+func scoreEntries(e []util.Entry, g *config.GeneralModule, text string) []util.Entry {
+	toPush := []util.Entry{}
+
+	for k := range e {
+		e[k].Module = g.Name
+
+		// entries may set their own weight, f.e. via application overrides
+		if e[k].Weight == 0 {
+			e[k].Weight = g.Weight
+		}
+
+		if e[k].DragDrop && !elements.grid.CanTarget() {
+			elements.grid.SetCanTarget(true)
+		}
+
+		toMatch := text
+
+		if e[k].MatchFields > 0 {
+			textFields := strings.Fields(text)
+
+			if len(textFields) > 0 {
+				toMatch = strings.Join(textFields[:1], " ")
+			}
+		}
+
+		if e[k].RecalculateScore {
+			e[k].ScoreFinal = 0
+			e[k].ScoreFuzzy = 0
+		}
+
+		if e[k].ScoreFinal == 0 {
+			switch e[k].Matching {
+			case util.AlwaysTopOnEmptySearch:
+				if text != "" {
+					e[k].ScoreFinal = fuzzyScore(e[k], toMatch)
+				} else {
+					e[k].ScoreFinal = 1000
+				}
+			case util.Fuzzy:
+				e[k].ScoreFinal = fuzzyScore(e[k], toMatch)
+			case util.AlwaysTop:
+				if e[k].ScoreFinal == 0 {
+					e[k].ScoreFinal = 1000
+				}
+			case util.AlwaysBottom:
+				if e[k].ScoreFinal == 0 {
+					e[k].ScoreFinal = 1
+				}
+			default:
+				e[k].ScoreFinal = 0
+			}
+		}
+
+		if e[k].ScoreFinal != 0 {
+			toPush = append(toPush, e[k])
+		}
+	}
+
+	return toPush
+}
+
+// streamEntries shows the batches of a streaming module as they arrive, the final list is set once processing is done.
+func streamEntries(ctx context.Context, st modules.Streamer, g *config.GeneralModule, text string, handler *Handler) {
+	batches := make(chan []util.Entry)
+
+	go func() {
+		st.Stream(ctx, text, batches)
+		close(batches)
+	}()
+
+	for batch := range batches {
+		handler.mut.Lock()
+		handler.entries = append(handler.entries, scoreEntries(batch, g, text)...)
+		entries := slices.Clone(handler.entries)
+		handler.mut.Unlock()
+
+		if !appstate.KeepSort && !handler.keepSort {
+			sortEntries(entries)
+		}
+
+		if len(entries) > cfg.List.MaxEntries {
+			entries = entries[:cfg.List.MaxEntries]
+		}
+
+		glib.IdleAdd(func() {
+			if ctx.Err() == nil {
+				common.items.Splice(0, int(common.items.NItems()), entries...)
+			}
+		})
+	}
+}
+
+func setTypeahead(ctx context.Context, mods []modules.Workable) {
+	text := elements.input.Text()
+
+	if text == "" {
 		return
 	}
 
 	toSet := ""
+	trimmed := strings.TrimSpace(text)
 
-	for _, v := range modules {
+	for _, v := range mods {
 		if v.General().Typeahead {
 			tah := history.GetInputHistory(v.General().Name)
 
-			trimmed := strings.TrimSpace(elements.input.Text())
-
 			if trimmed != "" {
 				for _, v := range tah {
 					if strings.HasPrefix(v.Term, trimmed) {
@@ -780,57 +955,124 @@ func setTypeahead(modules []modules.Workable) {
 						tahSuggestionIdentifier = v.Identifier
 					}
 				}
+			}
+		}
+	}
 
-				glib.IdleAdd(func() {
-					if trimmed != toSet {
-						elements.typeahead.SetText(toSet)
-					}
-				})
+	if toSet == "" || toSet == trimmed {
+		for _, v := range mods {
+			if c, ok := v.(modules.Completer); ok {
+				if res := c.Complete(ctx, text); res != "" {
+					toSet = res
+					tahSuggestionIdentifier = ""
+					break
+				}
 			}
 		}
 	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	glib.IdleAdd(func() {
+		if trimmed != toSet {
+			elements.typeahead.SetText(toSet)
+		}
+	})
 }
 
-func setInitials() {
-	entries := []util.Entry{}
+// initialModules returns the modules to show entries of before anything is typed: of the explicit modules, the ones
+// providing initial entries or with 'show_initial_entries', otherwise all modules with 'show_initial_entries'.
+func initialModules() []modules.Workable {
+	res := []modules.Workable{}
 
-	proc := findModule("applications", toUse)
+	if len(explicits) > 0 {
+		for _, v := range explicits {
+			if _, ok := v.(modules.Initial); ok || v.General().ShowInitialEntries {
+				res = append(res, v)
+			}
+		}
 
-	if proc == nil {
-		return
+		return res
 	}
 
-	if !proc.General().IsSetup {
-		proc.SetupData(cfg, nil)
+	for _, v := range toUse {
+		if v.General().ShowInitialEntries {
+			res = append(res, v)
+		}
 	}
 
-	e := proc.Entries(nil, "")
+	return res
+}
 
-	for _, entry := range e {
-		entry.Module = proc.General().Name
+func setInitials(mods []modules.Workable) {
+	entries := []util.Entry{}
 
-		for _, v := range hstry {
-			if val, ok := v[entry.Identifier()]; ok {
-				if entry.LastUsed.IsZero() || val.LastUsed.After(entry.LastUsed) {
-					entry.Used = val.Used
-					entry.DaysSinceUsed = val.DaysSinceUsed
-					entry.LastUsed = val.LastUsed
+	for _, proc := range mods {
+		g := proc.General()
+
+		if !g.IsSetup {
+			proc.SetupData(cfg, context.Background())
+		}
+
+		var e []util.Entry
+
+		if i, ok := proc.(modules.Initial); ok {
+			e = i.InitialEntries(context.Background())
+		} else {
+			e = proc.Entries(context.Background(), "")
+		}
+
+		moduleEntries := []util.Entry{}
+
+		for _, entry := range e {
+			entry.Module = g.Name
+
+			if entry.Weight == 0 {
+				entry.Weight = g.Weight
+			}
+
+			for _, v := range hstry {
+				if val, ok := v[entry.Identifier()]; ok {
+					if entry.LastUsed.IsZero() || val.LastUsed.After(entry.LastUsed) {
+						entry.Used = val.Used
+						entry.DaysSinceUsed = val.DaysSinceUsed
+						entry.LastUsed = val.LastUsed
+					}
 				}
 			}
+
+			entry.ScoreFinal = float64(usageModifier(entry))
+
+			moduleEntries = append(moduleEntries, entry)
+		}
+
+		if _, ordered := proc.(modules.OrderedInitial); !g.KeepSort && !ordered {
+			sortEntries(moduleEntries)
 		}
 
-		entry.ScoreFinal = float64(usageModifier(entry))
+		if g.MaxInitialEntries > 0 && len(moduleEntries) > g.MaxInitialEntries {
+			moduleEntries = moduleEntries[:g.MaxInitialEntries]
+		}
 
-		entries = append(entries, entry)
+		entries = append(entries, moduleEntries...)
 	}
 
-	if len(entries) == 0 {
-		return
+	if len(mods) > 1 {
+		sortEntries(entries)
 	}
 
-	sortEntries(entries)
+	if len(entries) > cfg.List.MaxEntries {
+		entries = entries[:cfg.List.MaxEntries]
+	}
 
 	common.items.Splice(0, int(common.items.NItems()), entries...)
+
+	// the current window comes first, select the previous one
+	if appstate.AltTab && len(entries) > 1 {
+		common.selection.SetSelected(1)
+	}
 }
 
 func usageModifier(item util.Entry) int {
@@ -872,6 +1114,10 @@ func quit() {
 
 	appstate.ExplicitModules = []string{}
 	appstate.ExplicitPlaceholder = ""
+	appstate.ExplicitRoots = nil
+	appstate.ExplicitDirsOnly = false
+	appstate.OpenWith = ""
+	appstate.AltTab = false
 	appstate.IsDmenu = false
 
 	explicits = []modules.Workable{}
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os/exec"
+	"strings"
+
+	"github.com/abenz1267/walker/internal/config"
+	"github.com/abenz1267/walker/internal/util"
+)
+
+// launchCommand creates the command running toRun according to the configured launch strategy. Environment
+// overrides of the entry are passed through 'env', so they also apply when the prefix starts the command elsewhere,
+// f.e. as a systemd service.
+func launchCommand(entry util.Entry, toRun string) *exec.Cmd {
+	args := []string{}
+
+	switch cfg.Launch.Strategy {
+	case config.LaunchSystemd:
+		if path, _ := exec.LookPath("systemd-run"); path != "" {
+			args = append(args, "systemd-run", "--user", "--scope", "--quiet", "--collect", "--slice=app.slice", fmt.Sprintf("--unit=%s", scopeName(entry)), "--")
+		} else {
+			log.Println("launch: systemd-run not found, starting directly")
+		}
+	case config.LaunchPrefix:
+		args = append(args, strings.Fields(cfg.Launch.Prefix)...)
+	}
+
+	args = append(args, shellArgs(entry, toRun)...)
+
+	return exec.Command(args[0], args[1:]...)
+}
+
+// shellArgs returns the arguments running toRun with the shell and the entry's environment overrides.
+func shellArgs(entry util.Entry, toRun string) []string {
+	args := []string{}
+
+	if len(entry.Env) > 0 {
+		args = append(args, "env")
+		args = append(args, entry.Env...)
+	}
+
+	return append(args, "sh", "-c", toRun)
+}
+
+// scopeName follows the 'app-<launcher>-<ApplicationID>-<RANDOM>.scope' naming convention for applications, using
+// the desktop file ID or the module name.
+func scopeName(entry util.Entry) string {
+	id := strings.TrimSuffix(entry.DesktopID, ".desktop")
+
+	if id == "" {
+		id = entry.Module
+	}
+
+	if id == "" {
+		id = "walker"
+	}
+
+	return fmt.Sprintf("app-walker-%s-%08x.scope", escapeUnitName(id), rand.Uint32())
+}
+
+// escapeUnitName escapes characters not allowed in unit names, and '-' as it's the separator, like systemd-escape.
+func escapeUnitName(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == ':', c == '_':
+			b.WriteByte(c)
+		case c == '.' && i > 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		}
+	}
+
+	return b.String()
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"slices"
 
 	"github.com/abenz1267/walker/internal/config"
@@ -17,7 +18,7 @@ func setupModules() {
 	setAvailables(cfg)
 	toUse = []modules.Workable{}
 
-	if len(appstate.ExplicitModules) > 0 {
+	if !setOpenWith() && len(appstate.ExplicitModules) > 0 {
 		setExplicits()
 	}
 
@@ -33,6 +34,9 @@ func setupModules() {
 		toUse = available
 	}
 
+	setFinderOptions()
+	setCwd()
+
 	if len(toUse) == 1 {
 		text := toUse[0].General().Placeholder
 		if appstate.ExplicitPlaceholder != "" {
@@ -75,6 +79,8 @@ func setAvailables(cfg *config.Config) {
 		&modules.Commands{},
 		&modules.SSH{},
 		&modules.Finder{},
+		&modules.ContentSearch{},
+		&modules.Recent{},
 		&modules.Switcher{},
 		&emojis.Emojis{},
 		&modules.CustomCommands{},
@@ -160,6 +166,46 @@ func setExplicits() {
 	}
 }
 
+// setOpenWith replaces the modules with a menu of the applications able to open the file given with --open-with.
+func setOpenWith() bool {
+	if appstate.OpenWith == "" {
+		return false
+	}
+
+	placeholder := fmt.Sprintf("Open %s with", filepath.Base(appstate.OpenWith))
+	explicits = []modules.Workable{modules.NewMenu(placeholder, modules.OpenWithEntries(appstate.OpenWith))}
+
+	return true
+}
+
+// rootSetter is implemented by modules working on the finder roots.
+type rootSetter interface {
+	SetExplicit(roots []string, dirsOnly bool)
+}
+
+// setFinderOptions passes the finder options given on the command line to the modules using them.
+func setFinderOptions() {
+	for _, v := range available {
+		if m, ok := v.(rootSetter); ok {
+			m.SetExplicit(appstate.ExplicitRoots, appstate.ExplicitDirsOnly)
+		}
+	}
+}
+
+// cwdSetter is implemented by modules resolving relative paths.
+type cwdSetter interface {
+	SetCwd(cwd string)
+}
+
+// setCwd passes the working directory of the client to the modules, the service's own one is unrelated.
+func setCwd() {
+	for _, v := range available {
+		if m, ok := v.(cwdSetter); ok {
+			m.SetCwd(appstate.Cwd)
+		}
+	}
+}
+
 func setupSingleModule() {
 	if len(explicits) != 1 && len(toUse) != 1 {
 		return
@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/abenz1267/walker/internal/util"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+// outputFeedClass marks entries putting their label into the search input, so it can be used with other modules.
+const outputFeedClass = "output_feed"
+
+// showOutput runs the command in the background and shows its output, one entry per line.
+func showOutput(entry util.Entry, toRun string) {
+	if !layout.Window.Box.Search.Spinner.Hide {
+		elements.spinner.SetVisible(true)
+	}
+
+	go func() {
+		lines, err := runForOutput(entry, toRun)
+
+		entries := []util.Entry{}
+
+		for _, v := range lines {
+			entries = append(entries, outputEntry(v))
+		}
+
+		placeholder := toRun
+
+		if err != nil {
+			placeholder = fmt.Sprintf("%s: %s", placeholder, err)
+		}
+
+		glib.IdleAdd(func() {
+			if !layout.Window.Box.Search.Spinner.Hide {
+				elements.spinner.SetVisible(false)
+			}
+
+			showMenu(placeholder, entries)
+		})
+	}()
+}
+
+// runForOutput runs the command and returns its combined stdout and stderr. Reading stops at the configured maximum
+// of lines, after the configured timeout or shortly after the command exited. The whole process group is killed when
+// it's still running, so it doesn't block on a full pipe.
+func runForOutput(entry util.Entry, toRun string) ([]string, error) {
+	args := shellArgs(entry, toRun)
+
+	cmd := exec.Command(args[0], args[1:]...)
+
+	if entry.Path != "" {
+		cmd.Dir = entry.Path
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+	}
+
+	setStdin(cmd, &entry.Piped)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.Close()
+
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.WaitDelay = 100 * time.Millisecond
+
+	err = cmd.Start()
+	w.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	read := make(chan struct{})
+
+	go func() {
+		defer close(read)
+
+		// closing the pipe ends commands writing more than is read
+		defer r.Close()
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+
+			if cfg.Output.MaxLines > 0 && len(lines) >= cfg.Output.MaxLines {
+				return
+			}
+		}
+	}()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	// background processes started by the command might keep the output open
+	drain := func() {
+		select {
+		case <-read:
+		case <-time.After(100 * time.Millisecond):
+			r.Close()
+			<-read
+		}
+	}
+
+	timeout := time.After(time.Duration(cfg.Output.Timeout) * time.Millisecond)
+	timedOut := fmt.Errorf("timed out after %dms", cfg.Output.Timeout)
+
+	select {
+	case <-read:
+		if cfg.Output.MaxLines > 0 && len(lines) >= cfg.Output.MaxLines {
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			<-done
+
+			return lines, nil
+		}
+
+		select {
+		case err = <-done:
+		case <-timeout:
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			<-done
+
+			err = timedOut
+		}
+	case err = <-done:
+		drain()
+	case <-timeout:
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		drain()
+
+		err = timedOut
+	}
+
+	return lines, err
+}
+
+// outputEntry copies the line when activated. Its actions feed it to other modules, run or open it.
+func outputEntry(line string) util.Entry {
+	copyLine := util.Entry{
+		Label: "Copy",
+		Sub:   line,
+		Exec:  "wl-copy",
+		Piped: util.Piped{Content: line, Type: "string"},
+	}
+
+	return util.Entry{
+		Label: line,
+		Exec:  copyLine.Exec,
+		Piped: copyLine.Piped,
+		Class: "output",
+		ActionsFunc: func() []util.Entry {
+			text := strings.TrimSpace(line)
+
+			actions := []util.Entry{
+				copyLine,
+				{
+					Label: "Search with all modules",
+					Sub:   text,
+					Class: outputFeedClass,
+					Exec:  text,
+				},
+				{
+					Label: "Run",
+					Sub:   text,
+					Exec:  text,
+				},
+			}
+
+			if u, err := url.Parse(text); (err == nil && u.Scheme != "" && u.Host != "") || util.FileExists(text) {
+				actions = append(actions, util.Entry{
+					Label: "Open",
+					Sub:   text,
+					Exec:  fmt.Sprintf("xdg-open %s", util.ShellQuote(text)),
+				})
+			}
+
+			return actions
+		},
+	}
+}
+
+// feedInput leaves the current menu and searches all modules for the text.
+func feedInput(text string) {
+	explicits = nil
+	resetSingleModule()
+
+	elements.input.SetObjectProperty("placeholder-text", cfg.Search.Placeholder)
+	elements.input.SetText(text)
+	elements.input.SetPosition(-1)
+	elements.input.GrabFocus()
+}
@@ -343,6 +343,7 @@ func setupFactory() *gtk.SignalListItemFactory {
 		}
 
 		boxClasses := []string{"item", val.Class}
+		boxClasses = append(boxClasses, val.Classes...)
 
 		if appstate.ActiveItem != nil && *appstate.ActiveItem >= 0 {
 			if item.Position() == uint(*appstate.ActiveItem) {
@@ -367,7 +368,9 @@ func setupFactory() *gtk.SignalListItemFactory {
 				ii := val.Icon
 
 				if ii == "" {
-					ii = findModule(val.Module, toUse).General().Icon
+					if module := findModule(val.Module, toUse, explicits); module != nil {
+						ii = module.General().Icon
+					}
 				}
 
 				if ii != "" {
@@ -531,13 +534,18 @@ func reopen() {
 		}
 	}()
 
-	if len(appstate.ExplicitModules) > 0 {
+	if setOpenWith() {
+		toUse = explicits
+	} else if len(appstate.ExplicitModules) > 0 {
 		setExplicits()
 		toUse = explicits
 	} else {
 		toUse = available
 	}
 
+	setFinderOptions()
+	setCwd()
+
 	setupSingleModule()
 
 	if singleModule != nil {
@@ -18,10 +18,12 @@ const (
 )
 
 type Entry struct {
+	Actions          []Entry      `mapstructure:"actions,omitempty" json:"actions,omitempty"`
 	Categories       []string     `mapstructure:"categories,omitempty" json:"categories,omitempty"`
 	Class            string       `mapstructure:"class,omitempty" json:"class,omitempty"`
 	DragDrop         bool         `mapstructure:"drag_drop,omitempty" json:"drag_drop,omitempty"`
 	DragDropData     string       `mapstructure:"drag_drop_data,omitempty" json:"drag_drop_data,omitempty"`
+	Env              []string     `mapstructure:"env,omitempty" json:"env,omitempty"`
 	Exec             string       `mapstructure:"exec,omitempty" json:"exec,omitempty"`
 	ExecAlt          string       `mapstructure:"exec_alt,omitempty" json:"exec_alt,omitempty"`
 	HideText         bool         `mapstructure:"hide_text,omitempty" json:"hide_text,omitempty"`
@@ -31,6 +33,7 @@ type Entry struct {
 	Label            string       `mapstructure:"label,omitempty" json:"label,omitempty"`
 	MatchFields      int          `mapstructure:"match_fields,omitempty" json:"match_fields,omitempty"`
 	Matching         MatchingType `mapstructure:"matching,omitempty" json:"matching,omitempty"`
+	Output           bool         `mapstructure:"output,omitempty" json:"output,omitempty"`
 	Path             string       `mapstructure:"path,omitempty" json:"path,omitempty"`
 	RecalculateScore bool         `mapstructure:"recalculate_score,omitempty" json:"recalculate_score,omitempty"`
 	ScoreFinal       float64      `mapstructure:"score_final,omitempty" json:"score_final,omitempty"`
@@ -38,18 +41,22 @@ type Entry struct {
 	Searchable       string       `mapstructure:"searchable,omitempty" json:"searchable,omitempty"`
 	Sub              string       `mapstructure:"sub,omitempty" json:"sub,omitempty"`
 	Terminal         bool         `mapstructure:"terminal,omitempty" json:"terminal,omitempty"`
+	TerminalTitle    string       `mapstructure:"terminal_title,omitempty" json:"terminal_title,omitempty"`
 	Prefer           bool         `mapstructure:"prefer,omitempty" json:"prefer,omitempty"`
 
 	// internal
+	ActionsFunc     func() []Entry            `mapstructure:"-" json:"-"`
+	Classes         []string                  `mapstructure:"-"`
 	DaysSinceUsed   int                       `mapstructure:"-"`
+	DesktopID       string                    `mapstructure:"-"`
 	History         bool                      `mapstructure:"-"`
 	LastUsed        time.Time                 `mapstructure:"-"`
 	Module          string                    `mapstructure:"-"`
 	OpenWindows     uint                      `mapstructure:"-"`
 	Piped           Piped                     `mapstructure:"-"`
 	PipedAlt        Piped                     `mapstructure:"-"`
-	SpecialFunc     func(args ...interface{}) `mapstructure:"-"`
-	SpecialFuncArgs []interface{}             `mapstructure:"-"`
+	SpecialFunc     func(args ...interface{}) `mapstructure:"-" json:"-"`
+	SpecialFuncArgs []interface{}             `mapstructure:"-" json:"-"`
 	Used            int                       `mapstructure:"-"`
 	Weight          int                       `mapstructure:"-"`
 }
@@ -65,3 +72,12 @@ type Piped struct {
 	Content string `mapstructure:"content,omitempty"`
 	Type    string `mapstructure:"type,omitempty"`
 }
+
+// ShellQuote quotes s so it is passed as a single word to `sh -c`.
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", `'\''`))
+}